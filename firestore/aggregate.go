@@ -0,0 +1,157 @@
+package firestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/query"
+)
+
+// maxAggregations is Firestore's limit of aggregations per query
+const maxAggregations = 5
+
+// aggregation represents a select list aggregate function computed by Firestore
+type aggregation struct {
+	function string
+	column   string
+	name     string
+}
+
+// aggregations returns aggregations of a select list consisting of COUNT(*), SUM(column) and AVG(column) only
+func aggregations(selectStmt *query.Select) ([]*aggregation, bool) {
+	if len(selectStmt.GroupBy) > 0 || len(selectStmt.List) == 0 || len(selectStmt.List) > maxAggregations {
+		return nil, false
+	}
+	var result []*aggregation
+	for _, item := range selectStmt.List {
+		call, ok := item.Expr.(*expr.Call)
+		if !ok || len(call.Args) != 1 {
+			return nil, false
+		}
+		anAggregation := &aggregation{function: strings.ToUpper(sqlparser.Stringify(call.X)), name: shared.ItemName(item)}
+		switch anAggregation.function {
+		case "COUNT":
+			if !isCountAll(call) {
+				return nil, false
+			}
+		case "SUM", "AVG":
+			column, ok := columnName(call.Args[0])
+			if !ok || IsDocIDColumn(column) {
+				return nil, false
+			}
+			anAggregation.column = column
+		default:
+			return nil, false
+		}
+		result = append(result, anAggregation)
+	}
+	return result, true
+}
+
+// isCountAll returns true for COUNT(*) or COUNT(<literal>)
+func isCountAll(call *expr.Call) bool {
+	switch call.Args[0].(type) {
+	case *expr.Star, *expr.Literal:
+		return true
+	}
+	return false
+}
+
+// aggregate runs aggregation query returning a single result keyed by column names
+func aggregate(ctx context.Context, reader *docReader, queryRef firestore.Query, items []*aggregation) (map[string]interface{}, error) {
+	aggregationQuery := queryRef.NewAggregationQuery()
+	for i, item := range items {
+		alias := fmt.Sprintf("a%d", i)
+		switch item.function {
+		case "COUNT":
+			aggregationQuery = aggregationQuery.WithCount(alias)
+		case "SUM":
+			aggregationQuery = aggregationQuery.WithSumPath(fieldPath(item.column), alias)
+		case "AVG":
+			aggregationQuery = aggregationQuery.WithAvgPath(fieldPath(item.column), alias)
+		}
+	}
+	aggregationResult, err := reader.aggregate(ctx, aggregationQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run aggregation query: %v", err)
+	}
+	var result = make(map[string]interface{}, len(items))
+	for i, item := range items {
+		result[item.name] = aggregationValue(aggregationResult[fmt.Sprintf("a%d", i)])
+	}
+	return result, nil
+}
+
+// aggregationValue converts aggregation result value into Go value
+func aggregationValue(value interface{}) interface{} {
+	protoValue, ok := value.(*pb.Value)
+	if !ok {
+		return value
+	}
+	switch actual := protoValue.ValueType.(type) {
+	case *pb.Value_IntegerValue:
+		return actual.IntegerValue
+	case *pb.Value_DoubleValue:
+		return actual.DoubleValue
+	}
+	return nil
+}
+
+// queryAggregate computes GROUP BY and aggregate functions of a select statement,
+// aggregate-only select list is computed by Firestore aggregation query, otherwise (or with a read time) matched documents are aggregated client side
+func (s *Statement) queryAggregate(ctx context.Context, reader *docReader, coll *collection, selectStmt *query.Select, args []driver.NamedValue) (driver.Rows, error) {
+	// ORDER BY, LIMIT and OFFSET apply to aggregated rows
+	aggregateStmt := *selectStmt
+	aggregateStmt.OrderBy, aggregateStmt.Limit, aggregateStmt.Offset = nil, nil, nil
+	plan, dryRun, err := buildFirestoreSelectQuery(coll, &aggregateStmt, args)
+	if err != nil {
+		return nil, err
+	}
+	plan.scanLimit = s.conn.cfg.ClientScanLimit
+	plan.reader = reader
+	limit, offset, err := queryWindow(selectStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	if items, ok := aggregations(selectStmt); ok && !dryRun && len(plan.queries) == 1 && plan.residual == nil {
+		result, err := aggregate(ctx, plan.reader, plan.queries[0], items)
+		if err != nil {
+			return nil, err
+		}
+		results = []map[string]interface{}{result}
+	} else {
+		var records []map[string]interface{}
+		err = plan.fetch(ctx, func(doc *firestore.DocumentSnapshot) (bool, error) {
+			records = append(records, documentData(doc))
+			return !dryRun, nil //just fetch one record
+		})
+		if err != nil {
+			return nil, err
+		}
+		if results, err = shared.Aggregate(selectStmt, records, havingArgs(selectStmt, convertNamedValuesToInterfaceSlice(args))); err != nil {
+			return nil, err
+		}
+	}
+	results = windowResults(results, limit, offset)
+	return NewRows(results, dryRun, selectStmt)
+}
+
+// havingArgs returns arguments following WHERE clause placeholders
+func havingArgs(selectStmt *query.Select, args []interface{}) []interface{} {
+	if selectStmt.Qualify == nil {
+		return args
+	}
+	if count := shared.Placeholders(selectStmt.Qualify.X); count < len(args) {
+		return args[count:]
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package firestore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatement_QueryAggregateNoValues(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		expect      []interface{}
+		// expectAggregations is the number of aggregation queries run by the server
+		expectAggregations int
+	}{
+		{
+			description:        "empty set",
+			SQL:                "SELECT COUNT(*), SUM(amount), AVG(amount) FROM orders WHERE status = 'none'",
+			expect:             []interface{}{int64(0), int64(0), nil},
+			expectAggregations: 1,
+		},
+		{
+			description:        "NULL values",
+			SQL:                "SELECT COUNT(*), SUM(amount), AVG(amount) FROM orders WHERE status = 'closed'",
+			expect:             []interface{}{int64(2), int64(0), nil},
+			expectAggregations: 1,
+		},
+		{
+			description:        "NULL values are ignored",
+			SQL:                "SELECT COUNT(*), SUM(amount), AVG(amount) FROM orders",
+			expect:             []interface{}{int64(4), int64(30), float64(15)},
+			expectAggregations: 1,
+		},
+		{
+			description: "empty set, client side",
+			SQL:         "SELECT COUNT(*), SUM(amount), AVG(amount) FROM orders WHERE LOWER(status) = 'none'",
+			expect:      []interface{}{int64(0), int64(0), nil},
+		},
+		{
+			description: "NULL values, client side",
+			SQL:         "SELECT COUNT(*), SUM(amount), AVG(amount) FROM orders WHERE LOWER(status) = 'closed'",
+			expect:      []interface{}{int64(2), int64(0), nil},
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.putData("orders/o1", map[string]interface{}{"status": "open", "amount": 10})
+	fake.putData("orders/o2", map[string]interface{}{"status": "open", "amount": 20})
+	fake.putData("orders/o3", map[string]interface{}{"status": "closed", "amount": nil})
+	fake.putData("orders/o4", map[string]interface{}{"status": "closed"})
+	for _, testCase := range testCases {
+		fake.aggregations = 0
+		actual := make([]interface{}, len(testCase.expect))
+		dest := make([]interface{}, len(actual))
+		for i := range actual {
+			dest[i] = &actual[i]
+		}
+		if err := db.QueryRow(testCase.SQL).Scan(dest...); err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+		if fake.aggregations != testCase.expectAggregations {
+			t.Errorf("%v: expected %v aggregation queries, but had %v", testCase.description, testCase.expectAggregations, fake.aggregations)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	// bulkBatchSize is the number of writes BulkWriter sends in a request
+	bulkBatchSize = 20
+	// defaultBulkMaxPending is the default number of pending BulkWriter batches
+	defaultBulkMaxPending = 25
+	// maxBulkErrors is the max number of document errors kept by BulkWriteError
+	maxBulkErrors = 100
+)
+
+// BulkWriteError is returned by a multi-document write some documents of which failed, the other documents are written
+type BulkWriteError struct {
+	// RowsAffected is the number of written documents
+	RowsAffected int64
+	// Failed is the number of failed documents
+	Failed int64
+	// Errors are errors of the first failed documents
+	Errors []error
+}
+
+// Error returns error message
+func (e *BulkWriteError) Error() string {
+	return fmt.Sprintf("failed to write %v document(s), %v written: %v", e.Failed, e.RowsAffected, e.Errors[0])
+}
+
+// Unwrap returns document errors, i.e. errors.Is(err, ErrDuplicateKey) matches a duplicate key of any document
+func (e *BulkWriteError) Unwrap() []error {
+	return e.Errors
+}
+
+// documentWriter writes documents matched by a multi-document UPDATE or DELETE
+type documentWriter interface {
+	update(docRef *firestore.DocumentRef, updates []firestore.Update, mode string)
+	delete(docRef *firestore.DocumentRef)
+	end() (int64, error)
+}
+
+// newWriter returns a document writer buffering writes within the connection transaction, or a bulk writer
+func (s *Statement) newWriter(ctx context.Context) documentWriter {
+	if s.conn.tx != nil {
+		return &txWriter{tx: s.conn.tx}
+	}
+	return newBulkWriter(ctx, s.conn.client, s.conn.cfg.BulkMaxPending)
+}
+
+// bulkJob represents an enqueued document write
+type bulkJob struct {
+	docRef *firestore.DocumentRef
+	job    *firestore.BulkWriterJob
+	err    error
+	// resolve returns whether a document was affected by the write of err outcome, nil counts successful writes
+	resolve func(err error) (bool, error)
+}
+
+// bulkWriter writes documents with firestore.BulkWriter, collecting per document errors;
+// at most maxPending writes are pending, once reached results of the oldest batch are awaited before more writes are enqueued
+type bulkWriter struct {
+	ctx    context.Context
+	client *firestore.Client
+	// writers are BulkWriters of the first, second, ... write of a document, BulkWriter rejects another write of a written document
+	writers []*firestore.BulkWriter
+	// writes are the numbers of enqueued writes by document path
+	writes     map[string]int
+	maxPending int
+	pending    []*bulkJob
+	bulkErr    BulkWriteError
+}
+
+// newBulkWriter creates a bulk writer with at most maxBatches pending batches of writes, 0 uses the default
+func newBulkWriter(ctx context.Context, client *firestore.Client, maxBatches int) *bulkWriter {
+	if maxBatches <= 0 {
+		maxBatches = defaultBulkMaxPending
+	}
+	return &bulkWriter{ctx: ctx, client: client, writes: map[string]int{}, maxPending: maxBatches * bulkBatchSize}
+}
+
+// writer returns the BulkWriter of the next write of a document
+func (w *bulkWriter) writer(docRef *firestore.DocumentRef) *firestore.BulkWriter {
+	path := documentPath(docRef)
+	index := w.writes[path]
+	w.writes[path] = index + 1
+	if index == len(w.writers) {
+		w.writers = append(w.writers, w.client.BulkWriter(w.ctx))
+	}
+	return w.writers[index]
+}
+
+// create enqueues a document creation, resolve handles the write outcome, i.e. an existing document
+func (w *bulkWriter) create(docRef *firestore.DocumentRef, data map[string]interface{}, resolve func(err error) (bool, error)) {
+	job, err := w.writer(docRef).Create(docRef, data)
+	w.add(&bulkJob{docRef: docRef, job: job, err: err, resolve: resolve})
+}
+
+// set enqueues a document replacement
+func (w *bulkWriter) set(docRef *firestore.DocumentRef, data map[string]interface{}) {
+	job, err := w.writer(docRef).Set(docRef, data)
+	w.add(&bulkJob{docRef: docRef, job: job, err: err})
+}
+
+// update enqueues field updates of a document with update mode
+func (w *bulkWriter) update(docRef *firestore.DocumentRef, updates []firestore.Update, mode string) {
+	var job *firestore.BulkWriterJob
+	var err error
+	switch mode {
+	case UpdateModeUpdate:
+		job, err = w.writer(docRef).Update(docRef, updates)
+	case UpdateModeMerge:
+		data, paths := updateData(updates)
+		job, err = w.writer(docRef).Set(docRef, data, firestore.Merge(paths...))
+	default:
+		data, _ := updateData(updates)
+		job, err = w.writer(docRef).Set(docRef, data)
+	}
+	w.add(&bulkJob{docRef: docRef, job: job, err: err})
+}
+
+// delete enqueues a document deletion
+func (w *bulkWriter) delete(docRef *firestore.DocumentRef) {
+	job, err := w.writer(docRef).Delete(docRef)
+	w.add(&bulkJob{docRef: docRef, job: job, err: err})
+}
+
+func (w *bulkWriter) add(job *bulkJob) {
+	w.pending = append(w.pending, job)
+	if len(w.pending) >= w.maxPending {
+		w.collect(bulkBatchSize)
+	}
+}
+
+// collect waits for results of the oldest count pending writes, resolving a write may enqueue another one
+func (w *bulkWriter) collect(count int) {
+	for ; count > 0 && len(w.pending) > 0; count-- {
+		pending := w.pending[0]
+		w.pending = w.pending[1:]
+		err := pending.err
+		if err == nil {
+			_, err = pending.job.Results()
+		}
+		affected := err == nil
+		if pending.resolve != nil {
+			affected, err = pending.resolve(err)
+		}
+		if affected {
+			w.bulkErr.RowsAffected++
+		}
+		if err == nil {
+			continue
+		}
+		w.bulkErr.Failed++
+		if len(w.bulkErr.Errors) < maxBulkErrors {
+			if _, ok := err.(*DuplicateKeyError); !ok {
+				err = fmt.Errorf("failed to write document %v: %w", documentPath(pending.docRef), err)
+			}
+			w.bulkErr.Errors = append(w.bulkErr.Errors, err)
+		}
+	}
+}
+
+// end writes pending documents and returns the number of written documents, BulkWriteError is returned if any document failed
+func (w *bulkWriter) end() (int64, error) {
+	for len(w.pending) > 0 {
+		for _, writer := range w.writers {
+			writer.Flush()
+		}
+		w.collect(len(w.pending))
+	}
+	for _, writer := range w.writers {
+		writer.End()
+	}
+	if w.bulkErr.Failed > 0 {
+		bulkErr := w.bulkErr
+		return bulkErr.RowsAffected, &bulkErr
+	}
+	return w.bulkErr.RowsAffected, nil
+}
@@ -0,0 +1,51 @@
+package firestore
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBulkWriter_Window(t *testing.T) {
+	fake, db := newFakeDB(t, "?bulkMaxPending=1")
+	SQL := "INSERT INTO users (id, name) VALUES (?, ?)" + strings.Repeat(", (?, ?)", 44)
+	var args []interface{}
+	for i := 0; i < 45; i++ {
+		args = append(args, "u"+strconv.Itoa(i), "n")
+	}
+	result, err := db.Exec(SQL, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 45 {
+		t.Errorf("expected 45 rows affected, but had %v", affected)
+	}
+	if docs := fake.snapshot(); len(docs) != 45 {
+		t.Errorf("expected 45 documents, but had %v", len(docs))
+	}
+}
+
+func TestBulkWriteError_Unwrap(t *testing.T) {
+	var testCases = []struct {
+		description string
+		errors      []error
+		expectIs    bool
+	}{
+		{
+			description: "duplicate key of a document",
+			errors:      []error{errors.New("unavailable"), &DuplicateKeyError{Path: "users/u1"}},
+			expectIs:    true,
+		},
+		{
+			description: "other errors",
+			errors:      []error{errors.New("unavailable")},
+		},
+	}
+	for _, testCase := range testCases {
+		err := error(&BulkWriteError{Failed: int64(len(testCase.errors)), Errors: testCase.errors})
+		if actual := errors.Is(err, ErrDuplicateKey); actual != testCase.expectIs {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expectIs, actual)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package firestore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+const (
+	// collectionGroupPrefix marks a table name as collection group, i.e. group:orders
+	collectionGroupPrefix = "group:"
+	// parentFunction is a pseudo-column returning parent document path of a row
+	parentFunction = "PARENT"
+	// parentKey is a result key of a parent document path
+	parentKey = "__parent__"
+)
+
+// collectionGroupExpr matches FROM COLLECTION_GROUP(name) table expression
+var collectionGroupExpr = regexp.MustCompile(`(?i)\bFROM\s+COLLECTION_GROUP\s*\(\s*([A-Za-z_][A-Za-z0-9_]*)\s*\)`)
+
+// ReplaceCollectionGroup rewrites FROM COLLECTION_GROUP(name) into FROM group:name
+func ReplaceCollectionGroup(SQL string) string {
+	return collectionGroupExpr.ReplaceAllString(SQL, "FROM "+collectionGroupPrefix+"$1")
+}
+
+// tableName returns a statement table name without quotes, SELECT table names keep backticks of quoted slash paths
+func tableName(stmt node.Node) string {
+	return strings.Trim(sqlparser.TableName(stmt), "`")
+}
+
+// collectionGroupID returns collection ID of a collection group table name
+func collectionGroupID(table string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(table), collectionGroupPrefix) {
+		return "", false
+	}
+	return table[len(collectionGroupPrefix):], true
+}
+
+// collection represents a queried collection or collection group
+type collection struct {
+	client *firestore.Client
+	// ref is nil for collection group
+	ref *firestore.CollectionRef
+	// group is collection ID of collection group
+	group string
+	query firestore.Query
+}
+
+func newCollection(client *firestore.Client, ref *firestore.CollectionRef) *collection {
+	return &collection{client: client, ref: ref, query: ref.Query}
+}
+
+func newCollectionGroup(client *firestore.Client, collectionID string) *collection {
+	return &collection{client: client, group: collectionID, query: client.CollectionGroup(collectionID).Query}
+}
+
+// isGroup returns true for collection group, documents of which are identified by path rather than ID
+func (c *collection) isGroup() bool {
+	return c.ref == nil
+}
+
+// documentKey returns document ID, or document path relative to the database for collection group
+func (c *collection) documentKey(result map[string]interface{}) interface{} {
+	id := result[DocIDColumn]
+	if !c.isGroup() {
+		return id
+	}
+	path := c.group + "/" + fmt.Sprintf("%v", id)
+	if parent, ok := result[parentKey].(string); ok && parent != "" {
+		path = parent + "/" + path
+	}
+	return path
+}
+
+// docRef returns reference of a document key returned by documentKey
+func (c *collection) docRef(key string) *firestore.DocumentRef {
+	if c.isGroup() {
+		return c.client.Doc(key)
+	}
+	return c.ref.Doc(key)
+}
+
+// documentData returns document fields with document ID and parent document path
+func documentData(doc *firestore.DocumentSnapshot) map[string]interface{} {
+	data := doc.Data()
+	data[DocIDColumn] = doc.Ref.ID
+	if parent := doc.Ref.Parent.Parent; parent != nil {
+		data[parentKey] = documentPath(parent)
+	}
+	return data
+}
+
+// documentPath returns document path relative to the database, i.e. users/u1
+func documentPath(docRef *firestore.DocumentRef) string {
+	path := docRef.Path
+	if index := strings.Index(path, "/documents/"); index != -1 {
+		return path[index+len("/documents/"):]
+	}
+	return path
+}
+
+// isParentColumn returns true for PARENT() pseudo-column
+func isParentColumn(n node.Node) bool {
+	call, ok := n.(*expr.Call)
+	return ok && len(call.Args) == 0 && strings.EqualFold(sqlparser.Stringify(call.X), parentFunction)
+}
+
+// parentColumns returns a copy of the expression with PARENT() pseudo-columns replaced by the parent document path key
+// of document data, so that client side evaluated conditions can reference the parent document
+func parentColumns(n node.Node) node.Node {
+	switch actual := n.(type) {
+	case *expr.Call:
+		if isParentColumn(actual) {
+			return &expr.Ident{Name: parentKey}
+		}
+		return &expr.Call{X: actual.X, Raw: actual.Raw, Args: parentColumns(actual.Args).([]node.Node)}
+	case *expr.Binary:
+		return &expr.Binary{X: parentColumns(actual.X), Op: actual.Op, Y: parentColumns(actual.Y)}
+	case *expr.Unary:
+		return &expr.Unary{Op: actual.Op, X: parentColumns(actual.X)}
+	case *expr.Parenthesis:
+		return &expr.Parenthesis{Raw: actual.Raw, X: parentColumns(actual.X)}
+	case *expr.Range:
+		return &expr.Range{Min: parentColumns(actual.Min), Max: parentColumns(actual.Max)}
+	case []node.Node:
+		result := make([]node.Node, len(actual))
+		for i, item := range actual {
+			result[i] = parentColumns(item)
+		}
+		return result
+	}
+	return n
+}
@@ -0,0 +1,157 @@
+package firestore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatement_QueryParent(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		expect      []string
+		expectError bool
+	}{
+		{
+			description: "parent column",
+			SQL:         "SELECT id, PARENT() AS owner FROM COLLECTION_GROUP(orders) WHERE amount = ?",
+			args:        []interface{}{"10"},
+			expect:      []string{"o1 users/u1"},
+		},
+		{
+			description: "parent condition is evaluated client side",
+			SQL:         "SELECT id, amount FROM COLLECTION_GROUP(orders) WHERE PARENT() = ?",
+			args:        []interface{}{"users/u2"},
+			expect:      []string{"o2 20"},
+		},
+		{
+			description: "parent condition within conjunction",
+			SQL:         "SELECT id, amount FROM COLLECTION_GROUP(orders) WHERE amount = ? AND LOWER(PARENT()) = ?",
+			args:        []interface{}{"20", "users/u1"},
+		},
+		{
+			description: "order by parent",
+			SQL:         "SELECT id, amount FROM COLLECTION_GROUP(orders) ORDER BY PARENT()",
+			expectError: true,
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1/orders/o1", map[string]string{"amount": "10"})
+	fake.put("users/u2/orders/o2", map[string]string{"amount": "20"})
+	for _, testCase := range testCases {
+		rows, err := db.Query(testCase.SQL, testCase.args...)
+		if testCase.expectError {
+			if err == nil {
+				rows.Close()
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []string
+		for rows.Next() {
+			var id, value string
+			if err = rows.Scan(&id, &value); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			actual = append(actual, id+" "+value)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
+
+func TestStatement_SlashPath(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		query       bool
+		expect      []string
+		expectDocs  map[string]map[string]string
+	}{
+		{
+			description: "select",
+			SQL:         "SELECT id, name FROM `tenants/t1/tasks`",
+			query:       true,
+			expect:      []string{"k1 a"},
+		},
+		{
+			description: "select with condition",
+			SQL:         "SELECT id, name FROM `tenants/t1/tasks` WHERE name = ?",
+			args:        []interface{}{"a"},
+			query:       true,
+			expect:      []string{"k1 a"},
+		},
+		{
+			description: "select by ID",
+			SQL:         "SELECT id, name FROM `tenants/t2/tasks` WHERE id = ?",
+			args:        []interface{}{"k2"},
+			query:       true,
+			expect:      []string{"k2 b"},
+		},
+		{
+			description: "collection group",
+			SQL:         "SELECT id, name FROM `group:tasks` WHERE name = ?",
+			args:        []interface{}{"b"},
+			query:       true,
+			expect:      []string{"k2 b"},
+		},
+		{
+			description: "insert",
+			SQL:         "INSERT INTO `tenants/t1/tasks` (id, name) VALUES (?, ?)",
+			args:        []interface{}{"k3", "c"},
+			expectDocs:  map[string]map[string]string{"tenants/t1/tasks/k1": {"name": "a"}, "tenants/t1/tasks/k3": {"name": "c"}, "tenants/t2/tasks/k2": {"name": "b"}},
+		},
+		{
+			description: "update",
+			SQL:         "UPDATE `tenants/t1/tasks` SET name = ? WHERE id = ?",
+			args:        []interface{}{"c", "k1"},
+			expectDocs:  map[string]map[string]string{"tenants/t1/tasks/k1": {"name": "c"}, "tenants/t2/tasks/k2": {"name": "b"}},
+		},
+		{
+			description: "delete",
+			SQL:         "DELETE FROM `tenants/t2/tasks` WHERE name = ?",
+			args:        []interface{}{"b"},
+			expectDocs:  map[string]map[string]string{"tenants/t1/tasks/k1": {"name": "a"}},
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		fake.put("tenants/t1/tasks/k1", map[string]string{"name": "a"})
+		fake.put("tenants/t2/tasks/k2", map[string]string{"name": "b"})
+		if !testCase.query {
+			if _, err := db.Exec(testCase.SQL, testCase.args...); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+				continue
+			}
+			if docs := fake.snapshot(); !reflect.DeepEqual(docs, testCase.expectDocs) {
+				t.Errorf("%v: expected documents %v, but had %v", testCase.description, testCase.expectDocs, docs)
+			}
+			continue
+		}
+		rows, err := db.Query(testCase.SQL, testCase.args...)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []string
+		for rows.Next() {
+			var id, name string
+			if err = rows.Scan(&id, &name); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			actual = append(actual, id+" "+name)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"google.golang.org/api/option"
 	"net/url"
 	"sync"
+	"time"
 )
 
 const (
@@ -21,6 +22,12 @@ const (
 	quotaProject    = "quotaProject"
 	scopes          = "scopes"
 	app             = "app"
+	clientScanLimit = "clientScanLimit"
+	nullMissingDocs = "nullMissingDocs"
+	updateModeKey   = "updateMode"
+	bulkMaxPending  = "bulkMaxPending"
+	txMaxAttempts   = "txMaxAttempts"
+	readTimeKey     = "readTime"
 	defaultApp      = "go-sql-bq"
 )
 
@@ -28,19 +35,25 @@ const (
 // If a new Config is created instead of being parsed from a DSN string,
 // the NewConfig function should be used, which sets default values.
 type Config struct {
-	CredentialsFile string // Username
-	Endpoint        string
-	APIKey          string
-	CredentialJSON  []byte
-	CredentialsURL  string
-	CredID          string //scy secret resource ID
-	CredentialsKey  string
-	UserAgent       string
-	ProjectID       string // project ID
-	QuotaProject    string
-	Scopes          []string
-	Location        string
-	App             string
+	CredentialsFile      string // Username
+	Endpoint             string
+	APIKey               string
+	CredentialJSON       []byte
+	CredentialsURL       string
+	CredID               string //scy secret resource ID
+	CredentialsKey       string
+	UserAgent            string
+	ProjectID            string // project ID
+	QuotaProject         string
+	Scopes               []string
+	Location             string
+	App                  string
+	ClientScanLimit      int       // max documents read for client side WHERE evaluation, 0 means unlimited
+	NullMissingDocuments bool      // return NULL rows for missing documents of WHERE id IN (...) lookup
+	UpdateMode           string    // default UPDATE mode: update, merge or replace
+	BulkMaxPending       int       // max number of pending BulkWriter batches of multi-document writes, 0 means default
+	TxMaxAttempts        int       // max attempts of a transaction aborted due to contention, 0 means Firestore default
+	ReadTime             time.Time // SELECT reads documents as they were at the time, zero reads the latest documents
 	url.Values
 }
 
@@ -8,6 +8,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"fmt"
+	"github.com/viant/firebase/shared"
 	"github.com/viant/sqlparser"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -19,6 +20,8 @@ type connection struct {
 	client *firestore.Client
 	mu     sync.Mutex
 	closed bool
+	// tx is the active transaction
+	tx *tx
 }
 
 // newConnection initializes a new connection to the Firestore
@@ -29,6 +32,11 @@ func newConnection(cfg *Config) (*connection, error) {
 	if cfg.hasCredentials() {
 		opts = append(opts, cfg.options()...)
 	}
+	readTimeOpts, err := readTimeOptions()
+	if err != nil {
+		return nil, fmt.Errorf("error initializing Firestore client: %w", err)
+	}
+	opts = append(opts, readTimeOpts...)
 
 	client, err := firestore.NewClient(ctx, cfg.ProjectID, opts...)
 	if err != nil {
@@ -50,8 +58,11 @@ func (c *connection) Prepare(query string) (driver.Stmt, error) {
 // PrepareContext prepares a statement with context.
 func (c *connection) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	stmtKind := sqlparser.ParseKind(query)
+	if isReplaceInto(query) {
+		stmtKind = sqlparser.KindInsert
+	}
 	stmt := &Statement{
-		SQL:  query,
+		SQL:  ReplaceUnset(ReplaceCollectionGroup(shared.EscapeFieldPaths(shared.ReplaceOperators(query)))),
 		kind: stmtKind,
 		conn: c,
 		ctx:  ctx,
@@ -68,6 +79,10 @@ func (c *connection) Close() error {
 		return driver.ErrBadConn
 	}
 	c.closed = true
+	// Roll back a transaction neither committed nor rolled back, it waits for completion otherwise
+	if c.tx != nil {
+		_ = c.tx.Rollback()
+	}
 	err := c.client.Close()
 	c.client = nil
 	return err
@@ -80,13 +95,15 @@ func (c *connection) Begin() (driver.Tx, error) {
 
 // BeginTx starts a transaction with options.
 func (c *connection) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	// Firestore transactions are handled asynchronously via RunTransaction.
-	// Return a tx wrapper that will manage this.
-	return &tx{
-		conn: c,
-		ctx:  ctx,
-		opts: opts,
-	}, nil
+	if c.tx != nil {
+		return nil, fmt.Errorf("transaction already in progress")
+	}
+	t, err := newTx(ctx, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.tx = t
+	return t, nil
 }
 
 // Ping verifies a connection to the database is still alive.
@@ -106,9 +123,17 @@ func (c *connection) Ping(ctx context.Context) error {
 	return nil
 }
 
-// CheckNamedValue checks if the named value is supported.
+// CheckNamedValue checks if the named value is supported, Map, Array, GeoPoint and Reference values
+// are converted into Firestore values, other driver.Valuer values use database/sql default conversion.
 func (c *connection) CheckNamedValue(nv *driver.NamedValue) error {
-	// All types are accepted
+	if value, ok := c.firestoreValue(nv.Value); ok {
+		nv.Value = value
+		return nil
+	}
+	if _, ok := nv.Value.(driver.Valuer); ok {
+		return driver.ErrSkip
+	}
+	// Other types, i.e. maps, slices, *latlng.LatLng or *firestore.DocumentRef, are accepted as is
 	return nil
 }
 
@@ -0,0 +1,240 @@
+package firestore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+	"github.com/viant/sqlparser/query"
+)
+
+const (
+	// cursorFunction is a pseudo-column returning continuation cursor of a row
+	cursorFunction = "CURSOR"
+	// cursorAfterFunction is a WHERE clause condition resuming a query after a continuation cursor
+	cursorAfterFunction = "CURSOR_AFTER"
+	// cursorKey is a result key of a continuation cursor, Firestore reserves field names matching __.*__
+	cursorKey = "__cursor__"
+)
+
+// cursor represents ORDER BY values and document ID of the last row of a page
+type cursor struct {
+	Values []interface{}
+}
+
+// cursorValue represents a typed cursor value
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+// encodeCursor returns an opaque continuation token of a result
+func encodeCursor(coll *collection, result map[string]interface{}, orderBy query.List) (string, error) {
+	var values []*cursorValue
+	for _, column := range cursorColumns(orderBy) {
+		columnValue := shared.Lookup(result, column)
+		if IsDocIDColumn(column) {
+			columnValue = coll.documentKey(result)
+		}
+		value, err := newCursorValue(columnValue)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode cursor: %v", err)
+		}
+		values = append(values, value)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes continuation token
+func decodeCursor(token string, orderBy query.List) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	var values []*cursorValue
+	if err = json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+	if len(values) != len(cursorColumns(orderBy)) {
+		return nil, fmt.Errorf("cursor does not match ORDER BY clause")
+	}
+	aCursor := &cursor{}
+	for _, value := range values {
+		decoded, err := value.decode()
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %v", err)
+		}
+		aCursor.Values = append(aCursor.Values, decoded)
+	}
+	return aCursor, nil
+}
+
+func newCursorValue(value interface{}) (*cursorValue, error) {
+	switch actual := value.(type) {
+	case nil:
+		return &cursorValue{Type: "null"}, nil
+	case bool:
+		return &cursorValue{Type: "bool", Value: strconv.FormatBool(actual)}, nil
+	case string:
+		return &cursorValue{Type: "string", Value: actual}, nil
+	case time.Time:
+		return &cursorValue{Type: "time", Value: actual.Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return &cursorValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(actual)}, nil
+	case float32, float64:
+		f, _ := shared.AsFloat64(actual)
+		return &cursorValue{Type: "float", Value: strconv.FormatFloat(f, 'g', -1, 64)}, nil
+	}
+	if _, ok := shared.AsFloat64(value); ok {
+		return &cursorValue{Type: "int", Value: fmt.Sprintf("%d", value)}, nil
+	}
+	return nil, fmt.Errorf("unsupported cursor value type: %T", value)
+}
+
+func (v *cursorValue) decode() (interface{}, error) {
+	switch v.Type {
+	case "null":
+		return nil, nil
+	case "bool":
+		return strconv.ParseBool(v.Value)
+	case "string":
+		return v.Value, nil
+	case "time":
+		return time.Parse(time.RFC3339Nano, v.Value)
+	case "bytes":
+		return base64.StdEncoding.DecodeString(v.Value)
+	case "float":
+		return strconv.ParseFloat(v.Value, 64)
+	case "int":
+		return strconv.ParseInt(v.Value, 10, 64)
+	}
+	return nil, fmt.Errorf("unsupported cursor value type: %v", v.Type)
+}
+
+// cursorColumns returns ORDER BY columns followed by document ID column unless already ordered by it
+func cursorColumns(orderBy query.List) []string {
+	var result []string
+	hasDocID := false
+	for _, item := range orderBy {
+		column := sqlparser.Stringify(item.Expr)
+		hasDocID = hasDocID || IsDocIDColumn(column)
+		result = append(result, column)
+	}
+	if !hasDocID {
+		result = append(result, DocIDColumn)
+	}
+	return result
+}
+
+// applyCursor orders the query by document ID (if not already ordered) and resumes it after the cursor,
+// document keys are converted into document references
+func applyCursor(coll *collection, queryRef firestore.Query, orderBy query.List, aCursor *cursor) firestore.Query {
+	columns := cursorColumns(orderBy)
+	if len(columns) > len(orderBy) {
+		direction := firestore.Asc
+		if len(orderBy) > 0 && strings.EqualFold(orderBy[len(orderBy)-1].Direction, "DESC") {
+			direction = firestore.Desc
+		}
+		queryRef = queryRef.OrderBy(firestore.DocumentID, direction)
+	}
+	values := make([]interface{}, len(aCursor.Values))
+	copy(values, aCursor.Values)
+	for i, column := range columns {
+		if key, ok := values[i].(string); ok && IsDocIDColumn(column) {
+			if docRef := coll.docRef(key); docRef != nil {
+				values[i] = docRef
+			}
+		}
+	}
+	return queryRef.StartAfter(values...)
+}
+
+// extractCursor removes CURSOR_AFTER(token) condition from a bound WHERE clause conjunction,
+// nil cursor is returned for absent or empty token
+func extractCursor(where node.Node, orderBy query.List) (node.Node, *cursor, error) {
+	if call, ok := where.(*expr.Call); ok && isCursorAfter(call) {
+		aCursor, err := cursorAfter(call, orderBy)
+		return nil, aCursor, err
+	}
+	binary, ok := where.(*expr.Binary)
+	if !ok || binary.Op != "AND" {
+		return where, nil, nil
+	}
+	x, xCursor, err := extractCursor(binary.X, orderBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, yCursor, err := extractCursor(binary.Y, orderBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	if xCursor == nil {
+		xCursor = yCursor
+	}
+	switch {
+	case x == nil:
+		return y, xCursor, nil
+	case y == nil:
+		return x, xCursor, nil
+	}
+	return &expr.Binary{X: x, Op: binary.Op, Y: y}, xCursor, nil
+}
+
+func isCursorAfter(call *expr.Call) bool {
+	return strings.EqualFold(sqlparser.Stringify(call.X), cursorAfterFunction)
+}
+
+func cursorAfter(call *expr.Call, orderBy query.List) (*cursor, error) {
+	if len(call.Args) != 1 {
+		return nil, fmt.Errorf("%s expects cursor argument", cursorAfterFunction)
+	}
+	value, err := shared.Evaluate(call.Args[0], nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s argument: %v", cursorAfterFunction, err)
+	}
+	token, ok := value.(string)
+	if !ok && value != nil {
+		return nil, fmt.Errorf("%s expects string cursor, but had: %T", cursorAfterFunction, value)
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return decodeCursor(token, orderBy)
+}
+
+// isCursorColumn returns true for CURSOR() pseudo-column
+func isCursorColumn(n node.Node) bool {
+	call, ok := n.(*expr.Call)
+	return ok && len(call.Args) == 0 && strings.EqualFold(sqlparser.Stringify(call.X), cursorFunction)
+}
+
+// addCursors adds continuation cursors to results if CURSOR() pseudo-column is selected
+func addCursors(coll *collection, selectStmt *query.Select, results []map[string]interface{}) error {
+	selected := false
+	for _, item := range selectStmt.List {
+		selected = selected || isCursorColumn(item.Expr)
+	}
+	if !selected {
+		return nil
+	}
+	for _, result := range results {
+		token, err := encodeCursor(coll, result, selectStmt.OrderBy)
+		if err != nil {
+			return err
+		}
+		result[cursorKey] = token
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package firestore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viant/sqlparser"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 5, 10, 7, 0, 123, time.UTC)
+	var testCases = []struct {
+		description string
+		SQL         string
+		result      map[string]interface{}
+		expect      []interface{}
+	}{
+		{
+			description: "document ID is appended",
+			SQL:         "SELECT * FROM t ORDER BY name",
+			result:      map[string]interface{}{"id": "d1", "name": "bob"},
+			expect:      []interface{}{"bob", "d1"},
+		},
+		{
+			description: "typed values",
+			SQL:         "SELECT * FROM t ORDER BY age DESC, score, active, created, data, missing",
+			result:      map[string]interface{}{"id": "d2", "age": int64(30), "score": 1.5, "active": true, "created": created, "data": []byte("x")},
+			expect:      []interface{}{int64(30), 1.5, true, created, []byte("x"), nil, "d2"},
+		},
+		{
+			description: "ordered by document ID",
+			SQL:         "SELECT * FROM t ORDER BY address.city, id DESC",
+			result:      map[string]interface{}{"id": "d3", "address": map[string]interface{}{"city": "LA"}},
+			expect:      []interface{}{"LA", "d3"},
+		},
+	}
+	client := newTestClient(t)
+	coll := newCollection(client, client.Collection("t"))
+	for _, testCase := range testCases {
+		selectStmt, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.description, err)
+		}
+		token, err := encodeCursor(coll, testCase.result, selectStmt.OrderBy)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		aCursor, err := decodeCursor(token, selectStmt.OrderBy)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(aCursor.Values, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, aCursor.Values)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	client := newTestClient(t)
+	coll := newCollection(client, client.Collection("t"))
+	selectStmt, err := sqlparser.ParseQuery("SELECT * FROM t ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := encodeCursor(coll, map[string]interface{}{"id": "d1", "name": "bob"}, selectStmt.OrderBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherStmt, err := sqlparser.ParseQuery("SELECT * FROM t ORDER BY name, age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var testCases = []struct {
+		description string
+		token       string
+		expectError string
+	}{
+		{description: "ORDER BY mismatch", token: token, expectError: "cursor does not match ORDER BY clause"},
+		{description: "not base64", token: "!", expectError: "invalid cursor"},
+		{description: "not JSON", token: "eA", expectError: "invalid cursor"},
+	}
+	for _, testCase := range testCases {
+		if _, err := decodeCursor(testCase.token, otherStmt.OrderBy); err == nil || !strings.Contains(err.Error(), testCase.expectError) {
+			t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+		}
+	}
+}
+
+func TestBuildFirestoreSelectQuery_Cursor(t *testing.T) {
+	client := newTestClient(t)
+	coll := newCollection(client, client.Collection("t"))
+	selectStmt, err := sqlparser.ParseQuery("SELECT name, CURSOR() FROM t WHERE a = ? AND CURSOR_AFTER(?) ORDER BY name DESC LIMIT 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := encodeCursor(coll, map[string]interface{}{"id": "d1", "name": "bob"}, selectStmt.OrderBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var testCases = []struct {
+		description   string
+		token         string
+		expectQueries []string
+	}{
+		{
+			description:   "resumed after cursor",
+			token:         token,
+			expectQueries: []string{"a == 1 ORDER BY name DESCENDING ORDER BY __name__ DESCENDING START AFTER ['bob', t/d1]"},
+		},
+		{
+			description:   "empty token starts from the first page",
+			token:         "",
+			expectQueries: []string{"a == 1 ORDER BY name DESCENDING"},
+		},
+	}
+	for _, testCase := range testCases {
+		plan, _, err := buildFirestoreSelectQuery(coll, selectStmt, namedValues(1, testCase.token))
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var queries []string
+		for _, queryRef := range plan.queries {
+			queries = append(queries, queryText(t, queryRef))
+		}
+		if !reflect.DeepEqual(queries, testCase.expectQueries) {
+			t.Errorf("%v: expected queries %q, but had %q", testCase.description, testCase.expectQueries, queries)
+		}
+	}
+}
+
+func TestStatement_QueryCursorByID(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		expect      []string
+	}{
+		{
+			description: "document by ID",
+			SQL:         "SELECT name, CURSOR() FROM users WHERE id = ?",
+			args:        []interface{}{"u1"},
+			expect:      []string{"a u1"},
+		},
+		{
+			description: "documents by IDs",
+			SQL:         "SELECT name, CURSOR() FROM users WHERE id IN (?, ?) ORDER BY name DESC",
+			args:        []interface{}{"u1", "u2"},
+			expect:      []string{"b u2", "a u1"},
+		},
+		{
+			description: "missing IDs are omitted",
+			SQL:         "SELECT name, CURSOR() FROM users WHERE id IN (?, ?, ?)",
+			args:        []interface{}{"u1", "u9", "u2"},
+			expect:      []string{"a u1", "b u2"},
+		},
+	}
+	client := newTestClient(t)
+	coll := newCollection(client, client.Collection("users"))
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1", map[string]string{"name": "a"})
+	fake.put("users/u2", map[string]string{"name": "b"})
+	for _, testCase := range testCases {
+		selectStmt, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := db.Query(testCase.SQL, testCase.args...)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []string
+		for rows.Next() {
+			var name, token string
+			if err = rows.Scan(&name, &token); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			// rows are identified by the document ID of which the token is the expected cursor
+			for _, id := range []string{"u1", "u2"} {
+				expectToken, err := encodeCursor(coll, map[string]interface{}{"id": id, "name": name}, selectStmt.OrderBy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if token == expectToken {
+					name += " " + id
+				}
+			}
+			actual = append(actual, name)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package firestore
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 
 	"github.com/viant/sqlparser"
+	"google.golang.org/api/iterator"
 )
 
 // Implementation of create collection operation
@@ -48,25 +50,29 @@ func (s *Statement) execDropTable(ctx context.Context, args []driver.NamedValue)
 
 	// Firestore does not have a delete collection operation
 	// We need to delete all documents within the collection
-	batch := s.conn.client.Batch()
+	writer := newBulkWriter(ctx, s.conn.client, s.conn.cfg.BulkMaxPending)
 
 	iter := collectionRef.Documents(ctx)
 	defer iter.Stop()
 
-	deletedCount := int64(0)
-
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if err == iterator.Done {
 			break
 		}
-		batch.Delete(doc.Ref)
-		deletedCount++
+		if err != nil {
+			err = fmt.Errorf("failed to drop collection %s: %v", collectionName, err)
+			if _, writeErr := writer.end(); writeErr != nil {
+				return nil, errors.Join(err, writeErr)
+			}
+			return nil, err
+		}
+		writer.delete(doc.Ref)
 	}
 
-	_, err = batch.Commit(ctx)
+	deletedCount, err := writer.end()
 	if err != nil {
-		return nil, fmt.Errorf("failed to drop collection %s: %v", collectionName, err)
+		return nil, fmt.Errorf("failed to drop collection %s: %w", collectionName, err)
 	}
 
 	return &Result{
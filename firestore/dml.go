@@ -4,36 +4,28 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"github.com/viant/firebase/shared"
 	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
 	"strings"
 
 	"cloud.google.com/go/firestore"
 	"github.com/viant/sqlparser"
 )
 
-// Implementation of insert operation
+// Implementation of insert operation: a document with ID is created and fails with DuplicateKeyError if it exists,
+// unless ON CONFLICT DO NOTHING, ON CONFLICT DO UPDATE SET or REPLACE INTO resolves the conflict
 func (s *Statement) execInsert(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	// Parse the INSERT statement
-	insertStmt, err := sqlparser.ParseInsert(s.SQL)
+	insertStmt, err := parseInsert(s.SQL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse insert statement: %v", err)
 	}
 
-	// Get the table selector which may contain document path expressions
-	selector := sqlparser.TableSelector(insertStmt)
-	var collectionRef *firestore.CollectionRef
-
-	if selector != nil && selector.Expression != "" {
-		// Handle collection[id=?].subcollection format
-		parent, subCollection, err := parseDocumentPath(selector, args)
-		if err != nil {
-			return nil, err
-		}
-		collectionRef = s.conn.client.Collection(parent).Doc(subCollection.docID).Collection(subCollection.collName)
-	} else {
-		// Standard collection reference
-		collectionName := sqlparser.TableName(insertStmt)
-		collectionRef = s.conn.client.Collection(collectionName)
+	// Get collection reference, the table may contain document path expressions
+	collectionRef, args, err := s.collectionReference(ctx, insertStmt.Statement, args, false)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare the evaluator with the provided arguments
@@ -52,15 +44,15 @@ func (s *Statement) execInsert(ctx context.Context, args []driver.NamedValue) (d
 	}
 	batchSize := valuesCount / columnsCount
 
+	// VALUES placeholders of all rows precede ON CONFLICT DO UPDATE SET placeholders
+	docIDs := make([]string, batchSize)
+	rows := make([]map[string]interface{}, batchSize)
 	for batchIndex := 0; batchIndex < batchSize; batchIndex++ {
 		data := make(map[string]interface{})
-		var customDocID string
-		hasCustomDocID := false
-
 		for colIndex := 0; colIndex < columnsCount; colIndex++ {
 			columnName := columnNames[colIndex]
 			valueExpr := insertStmt.Values[batchIndex*columnsCount+colIndex].Expr
-			value, err := eval.evaluateExprWithArgIndex(valueExpr, &argIndex)
+			value, err := eval.evaluateFieldValue(columnName, valueExpr, &argIndex)
 			if err != nil {
 				return nil, fmt.Errorf("failed to evaluate value for column %s: %v", columnNames[colIndex], err)
 			}
@@ -68,8 +60,7 @@ func (s *Statement) execInsert(ctx context.Context, args []driver.NamedValue) (d
 			// Check if this is a docid column
 			if IsDocIDColumn(columnName) {
 				if value != nil {
-					customDocID = fmt.Sprintf("%v", value)
-					hasCustomDocID = true
+					docIDs[batchIndex] = fmt.Sprintf("%v", value)
 				}
 				// Don't add docid to the values map
 				continue
@@ -77,16 +68,71 @@ func (s *Statement) execInsert(ctx context.Context, args []driver.NamedValue) (d
 
 			data[columnName] = value
 		}
+		rows[batchIndex] = data
+	}
+
+	// ON CONFLICT DO UPDATE SET placeholders are bound for each row
+	updates := make([][]firestore.Update, batchSize)
+	for batchIndex, data := range rows {
+		conflictArgIndex := argIndex
+		if updates[batchIndex], err = insertStmt.conflictUpdates(eval, data, &conflictArgIndex); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rows naming the same document ID are resolved before any write
+	if docIDs, rows, updates, err = insertStmt.uniqueRows(collectionRef, docIDs, rows, updates); err != nil {
+		return nil, err
+	}
+	batchSize = len(rows)
+
+	// Rows are buffered within a transaction
+	if t := s.conn.tx; t != nil {
+		if rowsAffected, lastInsertID, err = t.insert(insertStmt, collectionRef, docIDs, rows, updates); err != nil {
+			return nil, err
+		}
+		return &Result{
+			rowsAffected: rowsAffected,
+			insertID:     lastInsertID,
+		}, nil
+	}
 
-		var docRef *firestore.DocumentRef
+	// Multiple rows are written with BulkWriter, ON CONFLICT DO UPDATE SET rows are written one by one within transactions
+	if batchSize > 1 && insertStmt.conflict != conflictUpdate {
+		writer := newBulkWriter(ctx, s.conn.client, s.conn.cfg.BulkMaxPending)
+		for batchIndex, data := range rows {
+			customDocID := docIDs[batchIndex]
+			if customDocID == "" {
+				docRef := collectionRef.NewDoc()
+				writer.create(docRef, data, nil)
+				lastInsertID = docRef.ID
+				continue
+			}
+			insertStmt.bulkInsertDocument(writer, collectionRef.Doc(customDocID), data)
+			lastInsertID = customDocID
+		}
+		if rowsAffected, err = writer.end(); err != nil {
+			return nil, err
+		}
+		return &Result{
+			rowsAffected: rowsAffected,
+			insertID:     lastInsertID,
+		}, nil
+	}
 
+	for batchIndex, data := range rows {
 		// If a custom document ID was provided, use it
-		if hasCustomDocID && customDocID != "" {
-			docRef = collectionRef.Doc(customDocID)
-			_, err = docRef.Set(ctx, data)
+		if customDocID := docIDs[batchIndex]; customDocID != "" {
+			inserted, err := insertStmt.insertDocument(ctx, s.conn.client, collectionRef.Doc(customDocID), data, updates[batchIndex])
 			if err != nil {
+				if _, ok := err.(*DuplicateKeyError); ok {
+					return nil, err
+				}
 				return nil, fmt.Errorf("failed to insert values with custom ID: %v", err)
 			}
+			if !inserted {
+				continue
+			}
 			lastInsertID = customDocID
 		} else {
 			// Add a new document with auto-generated ID
@@ -108,32 +154,48 @@ func (s *Statement) execInsert(ctx context.Context, args []driver.NamedValue) (d
 
 // Implementation of update operation
 func (s *Statement) execUpdate(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	// Parse the UPDATE statement
-	updateStmt, err := sqlparser.ParseUpdate(s.SQL)
+	// Parse the UPDATE statement, the update mode is set by /*+ MERGE */ or /*+ REPLACE */ hint
+	mode, SQL := updateMode(s.SQL, s.conn.cfg.UpdateMode)
+	updateStmt, err := sqlparser.ParseUpdate(SQL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse update statement: %v", err)
 	}
 
-	// Get the table selector which may contain document path expressions
-	selector := sqlparser.TableSelector(updateStmt)
-	var collectionRef *firestore.CollectionRef
+	// Get collection reference, the table may contain document path expressions
+	collectionRef, args, err := s.collectionReference(ctx, updateStmt, args, false)
+	if err != nil {
+		return nil, err
+	}
 
-	if selector != nil && selector.Expression != "" {
-		// Handle collection[id=?].subcollection format
-		parent, subCollection, err := parseDocumentPath(selector, args)
+	// Prepare the updates; SET placeholders precede WHERE placeholders
+	updates := []firestore.Update{}
+	eval := &evaluator{args: convertNamedValuesToInterfaceSlice(args)}
+	argIndex := 0
+
+	for _, setItem := range updateStmt.Set {
+		col := sqlparser.Stringify(setItem.Column)
+		// Skip docid in updates
+		if IsDocIDColumn(col) {
+			continue
+		}
+
+		valueExpr := setItem.Expr
+		value, err := eval.evaluateFieldValue(col, valueExpr, &argIndex)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to evaluate value for column %s: %v", col, err)
 		}
-		collectionRef = s.conn.client.Collection(parent).Doc(subCollection.docID).Collection(subCollection.collName)
-	} else {
-		// Standard collection reference
-		collectionName := sqlparser.TableName(updateStmt)
-		collectionRef = s.conn.client.Collection(collectionName)
+		updates = append(updates, firestore.Update{
+			FieldPath: fieldPath(col),
+			Value:     value,
+		})
+	}
+	if err = validateUpdateMode(mode, updates); err != nil {
+		return nil, err
 	}
+	whereArgs := args[argIndex:]
 
 	// Check if we have a document ID in the WHERE clause
-	argsInterface := convertNamedValuesToInterfaceSlice(args)
-	docID, hasDocID, err := FindDocIDInWhere(updateStmt.Qualify, argsInterface)
+	docID, hasDocID, err := FindDocIDInWhere(updateStmt.Qualify, convertNamedValuesToInterfaceSlice(whereArgs))
 	if err != nil {
 		return nil, err
 	}
@@ -141,31 +203,11 @@ func (s *Statement) execUpdate(ctx context.Context, args []driver.NamedValue) (d
 	// If we have a document ID, update the document directly
 	if hasDocID {
 		docRef := collectionRef.Doc(docID)
-
-		// Prepare the updates
-		updates := []firestore.Update{}
-		eval := &evaluator{args: argsInterface}
-		argIndex := 0
-
-		for _, setItem := range updateStmt.Set {
-			col := sqlparser.Stringify(setItem.Column)
-			// Skip docid in updates
-			if IsDocIDColumn(col) {
-				continue
-			}
-
-			valueExpr := setItem.Expr
-			value, err := eval.evaluateExprWithArgIndex(valueExpr, &argIndex)
-			if err != nil {
-				return nil, fmt.Errorf("failed to evaluate value for column %s: %v", col, err)
-			}
-			updates = append(updates, firestore.Update{
-				Path:  col,
-				Value: value,
-			})
+		if t := s.conn.tx; t != nil {
+			err = t.update(docRef, updates, mode)
+		} else {
+			err = writeUpdates(ctx, docRef, updates, mode)
 		}
-
-		_, err = docRef.Update(ctx, updates)
 		if err != nil {
 			return nil, fmt.Errorf("failed to update document %s: %v", docID, err)
 		}
@@ -176,52 +218,25 @@ func (s *Statement) execUpdate(ctx context.Context, args []driver.NamedValue) (d
 	}
 
 	// Build the query based on WHERE clause
-	queryRef, err := buildFirestoreQuery(collectionRef, updateStmt.Qualify, args)
+	plan, err := buildFirestoreQuery(collectionRef, updateStmt.Qualify, whereArgs)
 	if err != nil {
 		return nil, err
 	}
-
-	// Fetch documents to update
-	docIter := queryRef.Documents(ctx)
-	defer docIter.Stop()
-
-	eval := &evaluator{args: convertNamedValuesToInterfaceSlice(args)}
-	argIndex := 0
-
-	rowsAffected := int64(0)
-
-	// Update each matching document
-	for {
-		doc, err := docIter.Next()
-		if err != nil {
-			break
-		}
-
-		updates := []firestore.Update{}
-
-		for _, setItem := range updateStmt.Set {
-			col := sqlparser.Stringify(setItem.Column)
-			// Skip docid in updates
-			if IsDocIDColumn(col) {
-				continue
-			}
-
-			valueExpr := setItem.Expr
-			value, err := eval.evaluateExprWithArgIndex(valueExpr, &argIndex)
-			if err != nil {
-				return nil, fmt.Errorf("failed to evaluate value for column %s: %v", col, err)
-			}
-			updates = append(updates, firestore.Update{
-				Path:  col,
-				Value: value,
-			})
-		}
-
-		_, err = doc.Ref.Update(ctx, updates)
-		if err != nil {
-			return nil, fmt.Errorf("failed to update document %s: %v", doc.Ref.ID, err)
-		}
-		rowsAffected++
+	plan.scanLimit = s.conn.cfg.ClientScanLimit
+	plan.reader = s.reader()
+
+	// Update each matching document with BulkWriter, or within the transaction
+	writer := s.newWriter(ctx)
+	err = plan.fetch(ctx, func(doc *firestore.DocumentSnapshot) (bool, error) {
+		writer.update(doc.Ref, updates, mode)
+		return true, nil
+	})
+	rowsAffected, writeErr := writer.end()
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
 	}
 
 	return &Result{
@@ -236,21 +251,10 @@ func (s *Statement) execDelete(ctx context.Context, args []driver.NamedValue) (d
 		return nil, fmt.Errorf("failed to parse delete statement: %v", err)
 	}
 
-	// Get the table selector which may contain document path expressions
-	selector := sqlparser.TableSelector(deleteStmt)
-	var collectionRef *firestore.CollectionRef
-
-	if selector != nil && selector.Expression != "" {
-		// Handle collection[id=?].subcollection format
-		parent, subCollection, err := parseDocumentPath(selector, args)
-		if err != nil {
-			return nil, err
-		}
-		collectionRef = s.conn.client.Collection(parent).Doc(subCollection.docID).Collection(subCollection.collName)
-	} else {
-		// Standard collection reference
-		collectionName := sqlparser.TableName(deleteStmt)
-		collectionRef = s.conn.client.Collection(collectionName)
+	// Get collection reference, the table may contain document path expressions
+	collectionRef, args, err := s.collectionReference(ctx, deleteStmt, args, false)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check if we have a document ID in the WHERE clause
@@ -263,7 +267,11 @@ func (s *Statement) execDelete(ctx context.Context, args []driver.NamedValue) (d
 	// If we have a document ID, delete the document directly
 	if hasDocID {
 		docRef := collectionRef.Doc(docID)
-		_, err = docRef.Delete(ctx)
+		if t := s.conn.tx; t != nil {
+			err = t.delete(docRef)
+		} else {
+			_, err = docRef.Delete(ctx)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to delete document %s: %v", docID, err)
 		}
@@ -274,29 +282,25 @@ func (s *Statement) execDelete(ctx context.Context, args []driver.NamedValue) (d
 	}
 
 	// Build the query based on WHERE clause
-	queryRef, err := buildFirestoreQuery(collectionRef, deleteStmt.Qualify, args)
+	plan, err := buildFirestoreQuery(collectionRef, deleteStmt.Qualify, args)
 	if err != nil {
 		return nil, err
 	}
-
-	// Fetch documents to delete
-	docIter := queryRef.Documents(ctx)
-	defer docIter.Stop()
-
-	rowsAffected := int64(0)
-
-	// Delete each matching document
-	for {
-		doc, err := docIter.Next()
-		if err != nil {
-			break
-		}
-
-		_, err = doc.Ref.Delete(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to delete document %s: %v", doc.Ref.ID, err)
-		}
-		rowsAffected++
+	plan.scanLimit = s.conn.cfg.ClientScanLimit
+	plan.reader = s.reader()
+
+	// Delete each matching document with BulkWriter, or within the transaction
+	writer := s.newWriter(ctx)
+	err = plan.fetch(ctx, func(doc *firestore.DocumentSnapshot) (bool, error) {
+		writer.delete(doc.Ref)
+		return true, nil
+	})
+	rowsAffected, writeErr := writer.end()
+	if err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
 	}
 
 	return &Result{
@@ -304,71 +308,88 @@ func (s *Statement) execDelete(ctx context.Context, args []driver.NamedValue) (d
 	}, nil
 }
 
-// Helper function to build Firestore query from WHERE clause
-func buildFirestoreQuery(collectionRef *firestore.CollectionRef, qualify *expr.Qualify, args []driver.NamedValue) (query firestore.Query, err error) {
+// Helper function to build Firestore query plan from WHERE clause
+func buildFirestoreQuery(collectionRef *firestore.CollectionRef, qualify *expr.Qualify, args []driver.NamedValue) (*queryPlan, error) {
+	plan := &queryPlan{queries: []firestore.Query{collectionRef.Query}}
 	if qualify == nil || qualify.X == nil {
 		// No WHERE clause; return all documents
-		return collectionRef.Query, nil
+		return plan, nil
 	}
 
-	eval := &evaluator{args: convertNamedValuesToInterfaceSlice(args)}
-	query = collectionRef.Query
-
 	// Check if the WHERE clause is just docid = value (already handled)
-	if binary, ok := qualify.X.(*expr.Binary); ok {
-		if binary.Op == "=" {
-			if ident, ok := binary.X.(*expr.Ident); ok && IsDocIDColumn(ident.Name) {
-				// Skip this clause as it's handled directly by document reference
-				return query, nil
-			}
-		}
+	where := shared.Normalize(qualify.X)
+	if isDocIDPredicate(where) {
+		// Skip this clause as it's handled directly by document reference
+		return plan, nil
 	}
+	return newPredicate(collectionRef, args).apply(collectionRef.Query, where)
+}
 
-	var argIndex = 0
-	switch amExpr := qualify.X.(type) {
-	case *expr.Binary:
-		colName, ok := amExpr.X.(*expr.Ident)
-		if !ok {
-			return query, fmt.Errorf("invalid column name in WHERE clause")
+// collectionReference returns collection reference of a statement table: a collection, a slash path (i.e. `tenants/t1/projects/p1/tasks`)
+// or a document path (i.e. tenants[id=?].projects[id=?].tasks); document ID placeholders precede any other placeholders,
+// they are bound from args in order and the remaining args are returned
+func (s *Statement) collectionReference(ctx context.Context, stmt node.Node, args []driver.NamedValue, dryRun bool) (*firestore.CollectionRef, []driver.NamedValue, error) {
+	if selector := sqlparser.TableSelector(stmt); selector != nil && selector.Expression != "" {
+		return parseDocumentPath(ctx, s.conn.client, selector, args, dryRun)
+	}
+	collectionName := tableName(stmt)
+	collectionRef := s.conn.client.Collection(collectionName)
+	if collectionRef == nil {
+		return nil, nil, fmt.Errorf("invalid collection path: %s", collectionName)
+	}
+	return collectionRef, args, nil
+}
+
+// parseDocumentPath parses collection[id=?].subcollection[id=?]...subcollection format of any depth,
+// in dry run mode document ID placeholders resolve to the first document of a collection
+func parseDocumentPath(ctx context.Context, client *firestore.Client, selector *expr.Selector, args []driver.NamedValue, dryRun bool) (*firestore.CollectionRef, []driver.NamedValue, error) {
+	var docRef *firestore.DocumentRef
+	for {
+		var collectionRef *firestore.CollectionRef
+		if docRef == nil {
+			collectionRef = client.Collection(selector.Name)
+		} else {
+			collectionRef = docRef.Collection(selector.Name)
 		}
-		// Skip docid in query conditions
-		if IsDocIDColumn(colName.Name) {
-			return query, nil
+		if collectionRef == nil {
+			return nil, nil, fmt.Errorf("invalid collection name: %s", selector.Name)
 		}
-
-		value, err := eval.evaluateExpr(amExpr.Y, &argIndex)
+		if selector.Expression == "" {
+			return nil, nil, fmt.Errorf("document ID expression not specified for collection: %s", selector.Name)
+		}
+		docID, rest, err := parseDocumentID(selector.Expression, args)
 		if err != nil {
-			return query, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+			return nil, nil, err
 		}
-		switch amExpr.Op {
-		case "=":
-			query = query.Where(colName.Name, "==", value)
-		case ">", ">=", "<", "<=":
-			query = query.Where(colName.Name, amExpr.Op, value)
+		if dryRun && len(rest) < len(args) {
+			if firstID, ok := firstDocumentID(ctx, collectionRef); ok {
+				docID = firstID
+			}
+		}
+		args = rest
+		if docRef = collectionRef.Doc(docID); docRef == nil {
+			return nil, nil, fmt.Errorf("invalid document ID: %s", docID)
+		}
+
+		// Get subcollection name
+		switch x := selector.X.(type) {
+		case *expr.Ident:
+			return docRef.Collection(x.Name), args, nil
+		case *expr.Selector:
+			selector = x
+		case nil:
+			return nil, nil, fmt.Errorf("subcollection not specified")
 		default:
-			return query, fmt.Errorf("unsupported operator in WHERE clause: %s", amExpr.Op)
+			return nil, nil, fmt.Errorf("unsupported subcollection format")
 		}
-	default:
-		return query, fmt.Errorf("unsupported WHERE clause")
 	}
-
-	return query, nil
 }
 
-// SubCollection represents a subcollection reference
-type SubCollection struct {
-	docID    string
-	collName string
-}
-
-// parseDocumentPath parses collection[id=?].subcollection format
-func parseDocumentPath(selector *expr.Selector, args []driver.NamedValue) (parentCollection string, subCollection *SubCollection, err error) {
-	parentCollection = selector.Name
-
-	// Parse expression to get document ID
-	exprParts := strings.Split(selector.Expression, "=")
+// parseDocumentID parses id=? or id='value' document path expression, placeholder is bound from the first arg
+func parseDocumentID(expression string, args []driver.NamedValue) (string, []driver.NamedValue, error) {
+	exprParts := strings.Split(expression, "=")
 	if len(exprParts) != 2 {
-		return "", nil, fmt.Errorf("invalid document path expression: %s", selector.Expression)
+		return "", nil, fmt.Errorf("invalid document path expression: %s", expression)
 	}
 
 	idField := strings.TrimSpace(exprParts[0])
@@ -379,45 +400,28 @@ func parseDocumentPath(selector *expr.Selector, args []driver.NamedValue) (paren
 	// Handle placeholder for document ID
 	docIDExpr := strings.TrimSpace(exprParts[1])
 	var docID string
-
 	if docIDExpr == "?" {
-		// Find the first placeholder value in args
-		for i, arg := range args {
-			docID = fmt.Sprintf("%v", arg.Value)
-			// Remove this argument as it's been used
-			if i < len(args)-1 {
-				args = append(args[:i], args[i+1:]...)
-			} else {
-				args = args[:i]
-			}
-			break
+		if len(args) == 0 {
+			return "", nil, fmt.Errorf("document ID not provided for: %s", expression)
 		}
+		docID = fmt.Sprintf("%v", args[0].Value)
+		args = args[1:]
 	} else {
 		// Direct value (without placeholder)
 		docID = strings.Trim(docIDExpr, "'\"")
 	}
-
 	if docID == "" {
 		return "", nil, fmt.Errorf("document ID not provided or is empty")
 	}
+	return docID, args, nil
+}
 
-	// Get subcollection name
-	if selector.X == nil {
-		return "", nil, fmt.Errorf("subcollection not specified")
-	}
-
-	switch x := selector.X.(type) {
-	case *expr.Ident:
-		subCollection = &SubCollection{
-			docID:    docID,
-			collName: x.Name,
-		}
-	case *expr.Selector:
-		// Nested path not supported yet
-		return "", nil, fmt.Errorf("nested subcollections not supported")
-	default:
-		return "", nil, fmt.Errorf("unsupported subcollection format")
+// firstDocumentID returns ID of the first document of a collection
+func firstDocumentID(ctx context.Context, collectionRef *firestore.CollectionRef) (string, bool) {
+	docIter := collectionRef.Limit(1).Documents(ctx)
+	defer docIter.Stop()
+	if doc, _ := docIter.Next(); doc != nil {
+		return doc.Ref.ID, true
 	}
-
-	return parentCollection, subCollection, nil
+	return "", false
 }
@@ -1,8 +1,13 @@
 package firestore
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/viant/firebase/shared"
 	"github.com/viant/sqlparser/expr"
 	"github.com/viant/sqlparser/insert"
+	"github.com/viant/sqlparser/node"
 	"github.com/viant/sqlparser/query"
 )
 
@@ -23,6 +28,14 @@ func HasDocIDInColumns(columns []string) bool {
 	return false
 }
 
+// validateDocID returns an error if a document ID contains a slash, such ID would address a document of another collection
+func validateDocID(docID string) error {
+	if strings.Contains(docID, "/") {
+		return fmt.Errorf("invalid document ID %q: must not contain '/'", docID)
+	}
+	return nil
+}
+
 // FindDocIDValueInInsert finds the document ID value in the INSERT statement values
 func FindDocIDValueInInsert(insertStmt *insert.Statement, args []interface{}) (string, bool, error) {
 	columnNames := insertStmt.Columns
@@ -71,42 +84,73 @@ func FindDocIDInWhere(qualify *expr.Qualify, args []interface{}) (string, bool,
 	}
 
 	// Process the WHERE clause to find docid = value condition
-	var docID string
-	var found bool
-	var err error
+	whereExpr := shared.Normalize(qualify.X)
+	if !isDocIDPredicate(whereExpr) {
+		return "", false, nil
+	}
 
+	// Extract the document ID value from the right side
 	argIndex := 0
-	// Process binary expression
-	switch whereExpr := qualify.X.(type) {
-	case *expr.Binary:
-		if whereExpr.Op != "=" {
-			return "", false, nil
-		}
-
-		// Check if left side is docid column
-		colName, ok := whereExpr.X.(*expr.Ident)
-		if !ok {
-			return "", false, nil
-		}
+	eval := &evaluator{args: args}
+	value, err := eval.evaluateExpr(whereExpr.(*expr.Binary).Y, &argIndex)
+	if err != nil {
+		return "", false, err
+	}
+	if value == nil {
+		return "", false, nil
+	}
+	docID := fmt.Sprintf("%v", value)
+	if err = validateDocID(docID); err != nil {
+		return "", false, err
+	}
+	return docID, true, nil
+}
 
-		if !IsDocIDColumn(colName.Name) {
-			return "", false, nil
+// FindDocIDsInWhere extracts document IDs from WHERE id IN (...) clause, duplicated IDs are removed
+func FindDocIDsInWhere(qualify *expr.Qualify, args []interface{}) ([]string, bool, error) {
+	if qualify == nil || qualify.X == nil {
+		return nil, false, nil
+	}
+	whereExpr := shared.Normalize(qualify.X)
+	binary, ok := whereExpr.(*expr.Binary)
+	if !ok || binary.Op != "IN" {
+		return nil, false, nil
+	}
+	if colName, ok := binary.X.(*expr.Ident); !ok || !IsDocIDColumn(colName.Name) {
+		return nil, false, nil
+	}
+	p := &predicate{eval: &evaluator{args: args}}
+	values, err := p.listValues(binary.Y)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not resolve document IDs in WHERE clause: %v", err)
+	}
+	var docIDs = make([]string, 0, len(values))
+	var seen = make(map[string]bool, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
 		}
-
-		// Extract the document ID value from the right side
-		eval := &evaluator{args: args}
-		value, err := eval.evaluateExpr(whereExpr.Y, &argIndex)
-		if err != nil {
-			return "", false, err
+		docID := fmt.Sprintf("%v", value)
+		if docID == "" || seen[docID] {
+			continue
 		}
-
-		if value != nil {
-			docID = value.(string)
-			found = true
+		if err = validateDocID(docID); err != nil {
+			return nil, false, err
 		}
+		seen[docID] = true
+		docIDs = append(docIDs, docID)
 	}
+	return docIDs, true, nil
+}
 
-	return docID, found, err
+// isDocIDPredicate returns true if expression is a single docid = value condition
+func isDocIDPredicate(n node.Node) bool {
+	binary, ok := n.(*expr.Binary)
+	if !ok || binary.Op != "=" {
+		return false
+	}
+	colName, ok := binary.X.(*expr.Ident)
+	return ok && IsDocIDColumn(colName.Name)
 }
 
 // AddDocIDToResults adds document ID to query results
@@ -0,0 +1,69 @@
+package firestore
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStatement_DocIDWithSlash(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		query       bool
+		expectError bool
+	}{
+		{
+			description: "document by ID",
+			SQL:         "SELECT id, name FROM users WHERE id = ?",
+			args:        []interface{}{"u1/orders"},
+			query:       true,
+			expectError: true,
+		},
+		{
+			description: "documents by IDs",
+			SQL:         "SELECT id, name FROM users WHERE id IN (?, ?)",
+			args:        []interface{}{"u1", "u2/orders/o1"},
+			query:       true,
+			expectError: true,
+		},
+		{
+			description: "documents by valid IDs",
+			SQL:         "SELECT id, name FROM users WHERE id IN (?, ?)",
+			args:        []interface{}{"u1", "u2"},
+			query:       true,
+		},
+		{
+			description: "update by ID",
+			SQL:         "UPDATE users SET name = ? WHERE id = ?",
+			args:        []interface{}{"b", "u1/orders/o1"},
+			expectError: true,
+		},
+		{
+			description: "delete by ID",
+			SQL:         "DELETE FROM users WHERE id = ?",
+			args:        []interface{}{"u1/orders/o1"},
+			expectError: true,
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1", map[string]string{"name": "a"})
+	fake.put("users/u1/orders/o1", map[string]string{"name": "o"})
+	for _, testCase := range testCases {
+		var err error
+		if testCase.query {
+			var rows *sql.Rows
+			if rows, err = db.Query(testCase.SQL, testCase.args...); err == nil {
+				rows.Close()
+			}
+		} else {
+			_, err = db.Exec(testCase.SQL, testCase.args...)
+		}
+		if (err != nil) != testCase.expectError {
+			t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+		}
+		if len(fake.writes) != 0 {
+			t.Errorf("%v: expected no writes, but had %v", testCase.description, len(fake.writes))
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -63,6 +65,37 @@ func ParseDSN(dsn string) (*Config, error) {
 		if _, ok := cfg.Values[scopes]; ok {
 			cfg.Scopes = cfg.Values[scopes]
 		}
+		if _, ok := cfg.Values[nullMissingDocs]; ok {
+			if cfg.NullMissingDocuments, err = strconv.ParseBool(cfg.Values.Get(nullMissingDocs)); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", nullMissingDocs, err)
+			}
+		}
+		if _, ok := cfg.Values[updateModeKey]; ok {
+			cfg.UpdateMode = strings.ToLower(cfg.Values.Get(updateModeKey))
+			if err = validateUpdateMode(cfg.UpdateMode, nil); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", updateModeKey, err)
+			}
+		}
+		if _, ok := cfg.Values[bulkMaxPending]; ok {
+			if cfg.BulkMaxPending, err = strconv.Atoi(cfg.Values.Get(bulkMaxPending)); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", bulkMaxPending, err)
+			}
+		}
+		if _, ok := cfg.Values[txMaxAttempts]; ok {
+			if cfg.TxMaxAttempts, err = strconv.Atoi(cfg.Values.Get(txMaxAttempts)); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", txMaxAttempts, err)
+			}
+		}
+		if _, ok := cfg.Values[readTimeKey]; ok {
+			if cfg.ReadTime, err = time.Parse(time.RFC3339Nano, cfg.Values.Get(readTimeKey)); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", readTimeKey, err)
+			}
+		}
+		if _, ok := cfg.Values[clientScanLimit]; ok {
+			if cfg.ClientScanLimit, err = strconv.Atoi(cfg.Values.Get(clientScanLimit)); err != nil {
+				return nil, fmt.Errorf("invalid %v: %v", clientScanLimit, err)
+			}
+		}
 	}
 
 	if cfg.CredentialsKey != "" {
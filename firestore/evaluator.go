@@ -3,7 +3,10 @@ package firestore
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
 	"github.com/viant/sqlparser/expr"
 	"github.com/viant/sqlparser/node"
 )
@@ -20,6 +23,8 @@ func (e *evaluator) evaluateExprWithArgIndex(n node.Node, argIndex *int) (interf
 	switch v := n.(type) {
 	case *expr.Literal:
 		return parseLiteralValue(v)
+	case *shared.Bound:
+		return v.Value, nil
 	case *expr.Placeholder:
 		if argIndex == nil {
 			return nil, fmt.Errorf("argIndex is nil")
@@ -30,11 +35,47 @@ func (e *evaluator) evaluateExprWithArgIndex(n node.Node, argIndex *int) (interf
 		value := e.args[*argIndex]
 		*argIndex++
 		return value, nil
+	case *expr.Binary, *expr.Unary, *expr.Parenthesis, *expr.Call:
+		// computed values, i.e. (EXCLUDED.n + 1) or UPPER(?), can not reference document fields
+		if column := columnReference(v); column != "" {
+			return nil, fmt.Errorf("unsupported column reference: %s", column)
+		}
+		bound, err := shared.Bind(v, e.args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return shared.Evaluate(bound, nil)
 	default:
 		return nil, fmt.Errorf("unsupported expression type: %T", v)
 	}
 }
 
+// columnReference returns the first column referenced by the expression, empty if none
+func columnReference(n node.Node) string {
+	switch actual := n.(type) {
+	case *expr.Ident, *expr.Selector:
+		return sqlparser.Stringify(actual)
+	case *expr.Binary:
+		if column := columnReference(actual.X); column != "" {
+			return column
+		}
+		return columnReference(actual.Y)
+	case *expr.Unary:
+		return columnReference(actual.X)
+	case *expr.Parenthesis:
+		return columnReference(actual.X)
+	case *expr.Call:
+		return columnReference(actual.Args)
+	case []node.Node:
+		for _, item := range actual {
+			if column := columnReference(item); column != "" {
+				return column
+			}
+		}
+	}
+	return ""
+}
+
 func parseLiteralValue(v *expr.Literal) (interface{}, error) {
 	switch v.Kind {
 	case "string":
@@ -44,6 +85,9 @@ func parseLiteralValue(v *expr.Literal) (interface{}, error) {
 		}
 		return str, nil
 	case "int", "numeric":
+		if strings.ContainsAny(v.Value, ".eE") {
+			return strconv.ParseFloat(v.Value, 64)
+		}
 		return strconv.ParseInt(v.Value, 10, 64)
 	case "float":
 		return strconv.ParseFloat(v.Value, 64)
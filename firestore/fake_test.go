@@ -0,0 +1,451 @@
+package firestore
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeServer is an in memory Firestore server of documents and transactions used by driver tests
+type fakeServer struct {
+	pb.UnimplementedFirestoreServer
+	mu   sync.Mutex
+	docs map[string]*pb.Document
+	// aborts is the number of commits to abort with codes.Aborted
+	aborts int
+	// onAbort is called before an aborted commit returns, i.e. to change documents read by the transaction
+	onAbort func()
+	// batchErr fails BatchWrite requests
+	batchErr error
+	// readTime is the read time of the last read request
+	readTime *timestamppb.Timestamp
+	// aggregations is the number of aggregation queries run
+	aggregations int
+	// queryErr fails RunQuery requests once matched documents are sent
+	queryErr error
+	// filters are filters of RunQuery requests as text
+	filters []string
+	// lastQuery is the structured query of the last RunQuery request
+	lastQuery *pb.StructuredQuery
+	// writes are writes of commit and batch write requests, failed writes included
+	writes    []*pb.Write
+	commits   int
+	rollbacks int
+	begins    int
+}
+
+func (f *fakeServer) BeginTransaction(ctx context.Context, req *pb.BeginTransactionRequest) (*pb.BeginTransactionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.begins++
+	return &pb.BeginTransactionResponse{Transaction: []byte("tx")}, nil
+}
+
+func (f *fakeServer) Rollback(ctx context.Context, req *pb.RollbackRequest) (*emptypb.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rollbacks++
+	return &emptypb.Empty{}, nil
+}
+
+func (f *fakeServer) Commit(ctx context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.aborts > 0 {
+		f.aborts--
+		if f.onAbort != nil {
+			f.onAbort()
+		}
+		return nil, status.Error(codes.Aborted, "transaction aborted")
+	}
+	f.writes = append(f.writes, req.Writes...)
+	for _, w := range req.Writes {
+		if err := f.check(w); err != nil {
+			return nil, err
+		}
+	}
+	f.commits++
+	for _, w := range req.Writes {
+		f.apply(w)
+	}
+	return &pb.CommitResponse{CommitTime: timestamppb.Now(), WriteResults: make([]*pb.WriteResult, len(req.Writes))}, nil
+}
+
+func (f *fakeServer) BatchWrite(ctx context.Context, req *pb.BatchWriteRequest) (*pb.BatchWriteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.batchErr != nil {
+		return nil, f.batchErr
+	}
+	resp := &pb.BatchWriteResponse{}
+	for _, w := range req.Writes {
+		writeStatus := &rpcstatus.Status{}
+		if err := f.check(w); err != nil {
+			writeStatus = status.Convert(err).Proto()
+		} else {
+			f.apply(w)
+		}
+		f.writes = append(f.writes, w)
+		resp.Status = append(resp.Status, writeStatus)
+		resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: timestamppb.Now()})
+	}
+	return resp, nil
+}
+
+// check verifies a write precondition
+func (f *fakeServer) check(w *pb.Write) error {
+	exists, ok := w.GetCurrentDocument().GetConditionType().(*pb.Precondition_Exists)
+	if !ok {
+		return nil
+	}
+	name := w.GetUpdate().GetName()
+	if name == "" {
+		name = w.GetDelete()
+	}
+	switch {
+	case !exists.Exists && f.docs[name] != nil:
+		return status.Errorf(codes.AlreadyExists, "document %v already exists", name)
+	case exists.Exists && f.docs[name] == nil:
+		return status.Errorf(codes.NotFound, "document %v not found", name)
+	}
+	return nil
+}
+
+// apply applies a write to documents
+func (f *fakeServer) apply(w *pb.Write) {
+	switch op := w.Operation.(type) {
+	case *pb.Write_Update:
+		doc := op.Update
+		if existing := f.docs[doc.Name]; existing != nil && w.UpdateMask != nil {
+			for _, fieldPath := range w.UpdateMask.FieldPaths {
+				setField(existing.Fields, fakeFieldPath(fieldPath), lookupField(doc.Fields, fakeFieldPath(fieldPath)))
+			}
+			existing.UpdateTime = timestamppb.Now()
+			return
+		}
+		doc.CreateTime, doc.UpdateTime = timestamppb.Now(), timestamppb.Now()
+		if doc.Fields == nil {
+			doc.Fields = map[string]*pb.Value{}
+		}
+		f.docs[doc.Name] = doc
+	case *pb.Write_Delete:
+		delete(f.docs, op.Delete)
+	}
+}
+
+func (f *fakeServer) BatchGetDocuments(req *pb.BatchGetDocumentsRequest, stream pb.Firestore_BatchGetDocumentsServer) error {
+	f.mu.Lock()
+	f.readTime = req.GetReadTime()
+	var responses []*pb.BatchGetDocumentsResponse
+	for _, name := range req.Documents {
+		response := &pb.BatchGetDocumentsResponse{Result: &pb.BatchGetDocumentsResponse_Missing{Missing: name}, ReadTime: timestamppb.Now()}
+		if doc := f.docs[name]; doc != nil {
+			response.Result = &pb.BatchGetDocumentsResponse_Found{Found: doc}
+		}
+		responses = append(responses, response)
+	}
+	f.mu.Unlock()
+	for _, response := range responses {
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunQuery returns documents of the queried collection or collection group matching equality filters, filters are recorded
+func (f *fakeServer) RunQuery(req *pb.RunQueryRequest, stream pb.Firestore_RunQueryServer) error {
+	f.mu.Lock()
+	f.readTime = req.GetReadTime()
+	f.filters = append(f.filters, filterText(req.GetStructuredQuery().GetWhere()))
+	f.lastQuery = req.GetStructuredQuery()
+	docs := f.query(req.Parent, req.GetStructuredQuery())
+	queryErr := f.queryErr
+	f.mu.Unlock()
+	for _, doc := range docs {
+		if err := stream.Send(&pb.RunQueryResponse{Document: doc, ReadTime: timestamppb.Now()}); err != nil {
+			return err
+		}
+	}
+	if queryErr != nil {
+		return queryErr
+	}
+	return stream.Send(&pb.RunQueryResponse{ReadTime: timestamppb.Now()})
+}
+
+// RunAggregationQuery returns counts, sums and averages of queried documents, non-numeric values are ignored
+func (f *fakeServer) RunAggregationQuery(req *pb.RunAggregationQueryRequest, stream pb.Firestore_RunAggregationQueryServer) error {
+	aggregationQuery := req.GetStructuredAggregationQuery()
+	f.mu.Lock()
+	f.readTime = req.GetReadTime()
+	f.aggregations++
+	docs := f.query(req.Parent, aggregationQuery.GetStructuredQuery())
+	f.mu.Unlock()
+	result := &pb.AggregationResult{AggregateFields: map[string]*pb.Value{}}
+	for _, aggregation := range aggregationQuery.Aggregations {
+		var value *pb.Value
+		switch {
+		case aggregation.GetCount() != nil:
+			value = fakeValue(int64(len(docs)))
+		case aggregation.GetSum() != nil:
+			value = fakeSum(docs, aggregation.GetSum().Field.FieldPath, false)
+		case aggregation.GetAvg() != nil:
+			value = fakeSum(docs, aggregation.GetAvg().Field.FieldPath, true)
+		}
+		result.AggregateFields[aggregation.Alias] = value
+	}
+	return stream.Send(&pb.RunAggregationQueryResponse{Result: result, ReadTime: timestamppb.Now()})
+}
+
+// query returns documents of the queried collection or collection group matching equality filters ordered by
+// order fields compared as text and by name, up to the query limit, with projected fields only
+func (f *fakeServer) query(parent string, query *pb.StructuredQuery) []*pb.Document {
+	from := query.From[0]
+	var docs []*pb.Document
+	for name, doc := range f.docs {
+		docParent, _ := path.Split(name)
+		inCollection := docParent == parent+"/"+from.CollectionId+"/"
+		if from.AllDescendants {
+			inCollection = strings.HasPrefix(docParent, parent+"/") && path.Base(docParent) == from.CollectionId
+		}
+		if inCollection && matchesFilter(doc, query.Where) {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	for k := len(query.GetOrderBy()) - 1; k >= 0; k-- {
+		order := query.OrderBy[k]
+		if order.Field.FieldPath == "__name__" {
+			continue
+		}
+		path := fakeFieldPath(order.Field.FieldPath)
+		descending := order.Direction == pb.StructuredQuery_DESCENDING
+		sort.SliceStable(docs, func(i, j int) bool {
+			left, right := valueText(lookupField(docs[i].Fields, path)), valueText(lookupField(docs[j].Fields, path))
+			if descending {
+				return left > right
+			}
+			return left < right
+		})
+	}
+	if limit := query.GetLimit(); limit != nil && int(limit.Value) < len(docs) {
+		docs = docs[:limit.Value]
+	}
+	if projection := query.GetSelect(); projection != nil {
+		for i, doc := range docs {
+			projected := &pb.Document{Name: doc.Name, Fields: map[string]*pb.Value{}, CreateTime: doc.CreateTime, UpdateTime: doc.UpdateTime}
+			for _, field := range projection.Fields {
+				if value := lookupField(doc.Fields, fakeFieldPath(field.FieldPath)); value != nil {
+					setField(projected.Fields, fakeFieldPath(field.FieldPath), value)
+				}
+			}
+			docs[i] = projected
+		}
+	}
+	return docs
+}
+
+// fakeSum returns the sum or average of numeric values of a field, the sum of no values is 0, their average null
+func fakeSum(docs []*pb.Document, fieldPath string, average bool) *pb.Value {
+	var intSum, count int64
+	var floatSum float64
+	isFloat := false
+	for _, doc := range docs {
+		switch value := lookupField(doc.Fields, fakeFieldPath(fieldPath)).GetValueType().(type) {
+		case *pb.Value_IntegerValue:
+			intSum += value.IntegerValue
+		case *pb.Value_DoubleValue:
+			floatSum += value.DoubleValue
+			isFloat = true
+		default:
+			continue
+		}
+		count++
+	}
+	switch {
+	case average && count == 0:
+		return fakeValue(nil)
+	case average:
+		return fakeValue((float64(intSum) + floatSum) / float64(count))
+	case isFloat:
+		return fakeValue(float64(intSum) + floatSum)
+	}
+	return fakeValue(intSum)
+}
+
+// matchesFilter returns true if a document matches conjunctions and disjunctions of equality filters, other filters match any document
+func matchesFilter(doc *pb.Document, filter *pb.StructuredQuery_Filter) bool {
+	if composite := filter.GetCompositeFilter(); composite != nil {
+		disjunction := composite.Op == pb.StructuredQuery_CompositeFilter_OR
+		for _, item := range composite.Filters {
+			if matchesFilter(doc, item) == disjunction {
+				return disjunction
+			}
+		}
+		return !disjunction
+	}
+	fieldFilter := filter.GetFieldFilter()
+	if fieldFilter == nil || fieldFilter.Op != pb.StructuredQuery_FieldFilter_EQUAL {
+		return true
+	}
+	if fieldPath := fieldFilter.Field.FieldPath; fieldPath == "__name__" {
+		return doc.Name == fieldFilter.Value.GetReferenceValue()
+	} else if value := lookupField(doc.Fields, fakeFieldPath(fieldPath)); value != nil {
+		return proto.Equal(value, fieldFilter.Value)
+	}
+	return false
+}
+
+// fakeFieldPath returns segments of a Firestore field path, segments can be quoted with backticks
+func fakeFieldPath(fieldPath string) []string {
+	var result []string
+	var segment strings.Builder
+	quoted := false
+	for _, r := range fieldPath {
+		switch {
+		case r == '`':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			result = append(result, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	return append(result, segment.String())
+}
+
+// lookupField returns a value of nested map fields, nil if missing
+func lookupField(fields map[string]*pb.Value, path []string) *pb.Value {
+	value := fields[path[0]]
+	if len(path) == 1 || value == nil {
+		return value
+	}
+	return lookupField(value.GetMapValue().GetFields(), path[1:])
+}
+
+// setField sets a value of nested map fields, a nil value deletes the field
+func setField(fields map[string]*pb.Value, path []string, value *pb.Value) {
+	if len(path) == 1 {
+		if value == nil {
+			delete(fields, path[0])
+		} else {
+			fields[path[0]] = value
+		}
+		return
+	}
+	if fields[path[0]].GetMapValue() == nil {
+		fields[path[0]] = &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: map[string]*pb.Value{}}}}
+	}
+	setField(fields[path[0]].GetMapValue().Fields, path[1:], value)
+}
+
+// fakeValue returns a Firestore value of a string, number, bool, nil, slice or map
+func fakeValue(value interface{}) *pb.Value {
+	switch actual := value.(type) {
+	case string:
+		return &pb.Value{ValueType: &pb.Value_StringValue{StringValue: actual}}
+	case int:
+		return &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: int64(actual)}}
+	case int64:
+		return &pb.Value{ValueType: &pb.Value_IntegerValue{IntegerValue: actual}}
+	case float64:
+		return &pb.Value{ValueType: &pb.Value_DoubleValue{DoubleValue: actual}}
+	case bool:
+		return &pb.Value{ValueType: &pb.Value_BooleanValue{BooleanValue: actual}}
+	case []interface{}:
+		array := &pb.ArrayValue{}
+		for _, item := range actual {
+			array.Values = append(array.Values, fakeValue(item))
+		}
+		return &pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: array}}
+	case map[string]interface{}:
+		fields := map[string]*pb.Value{}
+		for key, item := range actual {
+			fields[key] = fakeValue(item)
+		}
+		return &pb.Value{ValueType: &pb.Value_MapValue{MapValue: &pb.MapValue{Fields: fields}}}
+	}
+	return &pb.Value{ValueType: &pb.Value_NullValue{}}
+}
+
+// putData stores a document with fields of any fakeValue type
+func (f *fakeServer) putData(path string, data map[string]interface{}) {
+	doc := &pb.Document{Name: fakeDocumentName(path), Fields: fakeValue(data).GetMapValue().Fields, CreateTime: timestamppb.Now(), UpdateTime: timestamppb.Now()}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.docs[doc.Name] = doc
+}
+
+// put stores a document with string fields
+func (f *fakeServer) put(path string, fields map[string]string) {
+	doc := &pb.Document{Name: fakeDocumentName(path), Fields: map[string]*pb.Value{}, CreateTime: timestamppb.Now(), UpdateTime: timestamppb.Now()}
+	for name, value := range fields {
+		doc.Fields[name] = &pb.Value{ValueType: &pb.Value_StringValue{StringValue: value}}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.docs[doc.Name] = doc
+}
+
+// remove deletes a document
+func (f *fakeServer) remove(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.docs, fakeDocumentName(path))
+}
+
+// snapshot returns string fields of stored documents by document path
+func (f *fakeServer) snapshot() map[string]map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := map[string]map[string]string{}
+	for name, doc := range f.docs {
+		fields := map[string]string{}
+		for field, value := range doc.Fields {
+			fields[field] = value.GetStringValue()
+		}
+		result[strings.TrimPrefix(name, fakeDocumentName(""))] = fields
+	}
+	return result
+}
+
+// fakeDocumentName returns the full document name of a path of the fake database
+func fakeDocumentName(path string) string {
+	return "projects/p/databases/(default)/documents/" + path
+}
+
+// newFakeDB starts a fake server and returns it with a database of a single connection, params are appended to the DSN
+func newFakeDB(t *testing.T, params string) (*fakeServer, *sql.DB) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	fake := &fakeServer{docs: map[string]*pb.Document{}}
+	pb.RegisterFirestoreServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	t.Setenv("FIRESTORE_EMULATOR_HOST", listener.Addr().String())
+	db, err := sql.Open("firestore", "firestore://p/"+params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return fake, db
+}
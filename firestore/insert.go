@@ -0,0 +1,307 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/insert"
+	"github.com/viant/sqlparser/node"
+	"github.com/viant/sqlparser/update"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Conflict resolution of INSERT statement with an existing document
+const (
+	// conflictError fails INSERT with DuplicateKeyError
+	conflictError = iota
+	// conflictIgnore skips the row: ON CONFLICT DO NOTHING
+	conflictIgnore
+	// conflictUpdate updates ON CONFLICT DO UPDATE SET (or ON DUPLICATE KEY UPDATE) fields of the existing document
+	conflictUpdate
+	// conflictReplace replaces the existing document: REPLACE INTO
+	conflictReplace
+)
+
+// excludedTable references values of the row proposed for insertion in ON CONFLICT DO UPDATE SET, i.e. EXCLUDED.name
+const excludedTable = "EXCLUDED"
+
+var (
+	// replaceIntoExpr matches REPLACE INTO statement
+	replaceIntoExpr = regexp.MustCompile(`(?i)^\s*REPLACE\s+INTO\b`)
+	// onConflictExpr matches ON CONFLICT [(column)] DO NOTHING | DO UPDATE SET ... clause
+	onConflictExpr = regexp.MustCompile(`(?is)\s+ON\s+CONFLICT\s*(?:\(([^)]*)\))?\s*DO\s+(NOTHING|UPDATE\s+SET\s+(.+?))\s*$`)
+	// onDuplicateKeyExpr matches ON DUPLICATE KEY UPDATE ... clause
+	onDuplicateKeyExpr = regexp.MustCompile(`(?is)\s+ON\s+DUPLICATE\s+KEY\s+UPDATE\s+(.+?)\s*$`)
+	// negatedColumnExpr matches a quoted string or unary minus of a column, i.e. -EXCLUDED.n
+	negatedColumnExpr = regexp.MustCompile(`'[^']*'|([=(,+\-*/]\s*)-\s*([A-Za-z_][A-Za-z0-9_.]*)`)
+)
+
+// ErrDuplicateKey is matched by DuplicateKeyError with errors.Is
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// DuplicateKeyError is returned by INSERT of a document that already exists
+type DuplicateKeyError struct {
+	// Path is the document path relative to the database, i.e. users/u1
+	Path string
+}
+
+// Error returns error message
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key: document %v already exists", e.Path)
+}
+
+// Is returns true for ErrDuplicateKey
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+// insertStatement represents INSERT statement with its conflict resolution
+type insertStatement struct {
+	*insert.Statement
+	conflict int
+	// onConflict represents ON CONFLICT DO UPDATE SET items
+	onConflict []*update.Item
+}
+
+// isReplaceInto returns true for REPLACE INTO statement
+func isReplaceInto(SQL string) bool {
+	return replaceIntoExpr.MatchString(SQL)
+}
+
+// parseInsert parses INSERT, REPLACE INTO, INSERT ... ON CONFLICT and INSERT ... ON DUPLICATE KEY UPDATE statements
+func parseInsert(SQL string) (*insertStatement, error) {
+	result := &insertStatement{}
+	if isReplaceInto(SQL) {
+		SQL = replaceIntoExpr.ReplaceAllString(SQL, "INSERT INTO")
+		result.conflict = conflictReplace
+	}
+	setItems := ""
+	if match := onConflictExpr.FindStringSubmatchIndex(SQL); match != nil {
+		if match[2] != -1 {
+			if target := strings.TrimSpace(SQL[match[2]:match[3]]); target != "" && !IsDocIDColumn(target) {
+				return nil, fmt.Errorf("unsupported ON CONFLICT target: %v, only %v is supported", target, DocIDColumn)
+			}
+		}
+		result.conflict = conflictIgnore
+		if match[6] != -1 {
+			result.conflict = conflictUpdate
+			setItems = SQL[match[6]:match[7]]
+		}
+		SQL = SQL[:match[0]]
+	} else if match := onDuplicateKeyExpr.FindStringSubmatchIndex(SQL); match != nil {
+		result.conflict = conflictUpdate
+		setItems = SQL[match[2]:match[3]]
+		SQL = SQL[:match[0]]
+	}
+	var err error
+	if result.Statement, err = sqlparser.ParseInsert(SQL); err != nil {
+		return nil, err
+	}
+	if err = parseValueRows(SQL, result.Statement); err != nil {
+		return nil, err
+	}
+	if setItems != "" {
+		updateStmt, err := sqlparser.ParseUpdate("UPDATE t SET " + negateColumns(setItems))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse conflict update: %v", err)
+		}
+		result.onConflict = updateStmt.Set
+	}
+	return result, nil
+}
+
+// negateColumns rewrites unary minus of columns into subtraction from zero, sqlparser parses unary minus of numbers only
+func negateColumns(SQL string) string {
+	return negatedColumnExpr.ReplaceAllStringFunc(SQL, func(match string) string {
+		if strings.HasPrefix(match, "'") {
+			return match
+		}
+		groups := negatedColumnExpr.FindStringSubmatch(match)
+		return groups[1] + "(0 - " + groups[2] + ")"
+	})
+}
+
+// conflictUpdates evaluates ON CONFLICT DO UPDATE SET items of an inserted row, EXCLUDED.column references row values
+func (s *insertStatement) conflictUpdates(eval *evaluator, row map[string]interface{}, argIndex *int) ([]firestore.Update, error) {
+	var updates []firestore.Update
+	for _, item := range s.onConflict {
+		col := sqlparser.Stringify(item.Column)
+		if IsDocIDColumn(col) {
+			continue
+		}
+		value, err := eval.evaluateFieldValue(col, bindExcluded(item.Expr, row), argIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate conflict value for column %s: %v", col, err)
+		}
+		updates = append(updates, firestore.Update{FieldPath: fieldPath(col), Value: value})
+	}
+	return updates, nil
+}
+
+// bindExcluded replaces EXCLUDED.column references with inserted row values
+func bindExcluded(n node.Node, row map[string]interface{}) node.Node {
+	switch actual := n.(type) {
+	case *expr.Selector:
+		path := shared.FieldPath(sqlparser.Stringify(actual))
+		if len(path) > 1 && strings.EqualFold(path[0], excludedTable) {
+			return &shared.Bound{Value: shared.Lookup(row, strings.Join(path[1:], "."))}
+		}
+	case *expr.Binary:
+		return &expr.Binary{X: bindExcluded(actual.X, row), Op: actual.Op, Y: bindExcluded(actual.Y, row)}
+	case *expr.Unary:
+		return &expr.Unary{Op: actual.Op, X: bindExcluded(actual.X, row)}
+	case *expr.Parenthesis:
+		return &expr.Parenthesis{Raw: actual.Raw, X: bindExcluded(actual.X, row)}
+	case *expr.Call:
+		args := make([]node.Node, len(actual.Args))
+		for i, arg := range actual.Args {
+			args[i] = bindExcluded(arg, row)
+		}
+		return &expr.Call{X: actual.X, Args: args, Raw: actual.Raw}
+	}
+	return n
+}
+
+// insertDocument writes an inserted row with a document ID according to conflict resolution,
+// false is returned if the row was skipped
+func (s *insertStatement) insertDocument(ctx context.Context, client *firestore.Client, docRef *firestore.DocumentRef, data map[string]interface{}, updates []firestore.Update) (bool, error) {
+	switch s.conflict {
+	case conflictReplace:
+		_, err := docRef.Set(ctx, data)
+		return err == nil, err
+	case conflictUpdate:
+		return true, upsertDocument(ctx, client, docRef, data, updates)
+	}
+	_, err := docRef.Create(ctx, data)
+	return s.resolveConflict(docRef, err)
+}
+
+// upsertDocument creates a document or updates fields of the existing one within a transaction,
+// Firestore retries the transaction if the document changes in the meantime
+func upsertDocument(ctx context.Context, client *firestore.Client, docRef *firestore.DocumentRef, data map[string]interface{}, updates []firestore.Update) error {
+	return client.RunTransaction(ctx, func(ctx context.Context, transaction *firestore.Transaction) error {
+		doc, err := transaction.Get(docRef)
+		if doc == nil || !doc.Exists() {
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+			return transaction.Create(docRef, data)
+		}
+		return transaction.Update(docRef, updates)
+	})
+}
+
+// bulkInsertDocument enqueues an inserted row with a document ID according to conflict resolution
+func (s *insertStatement) bulkInsertDocument(writer *bulkWriter, docRef *firestore.DocumentRef, data map[string]interface{}) {
+	if s.conflict == conflictReplace {
+		writer.set(docRef, data)
+		return
+	}
+	writer.create(docRef, data, func(err error) (bool, error) {
+		return s.resolveConflict(docRef, err)
+	})
+}
+
+// resolveConflict resolves the outcome of a document creation: an existing document is skipped
+// or reported with DuplicateKeyError; false is returned if the row was skipped
+func (s *insertStatement) resolveConflict(docRef *firestore.DocumentRef, err error) (bool, error) {
+	if status.Code(err) != codes.AlreadyExists {
+		return err == nil, err
+	}
+	if s.conflict == conflictIgnore {
+		return false, nil
+	}
+	return false, &DuplicateKeyError{Path: documentPath(docRef)}
+}
+
+// uniqueRows resolves rows naming the same document ID: REPLACE INTO keeps the last row, ON CONFLICT DO NOTHING
+// the first row, otherwise DuplicateKeyError is returned
+func (s *insertStatement) uniqueRows(collectionRef *firestore.CollectionRef, docIDs []string, rows []map[string]interface{}, updates [][]firestore.Update) ([]string, []map[string]interface{}, [][]firestore.Update, error) {
+	positions := make(map[string]int, len(docIDs))
+	var resultIDs []string
+	var resultRows []map[string]interface{}
+	var resultUpdates [][]firestore.Update
+	for i, docID := range docIDs {
+		if docID != "" {
+			if position, ok := positions[docID]; ok {
+				switch s.conflict {
+				case conflictReplace:
+					resultRows[position], resultUpdates[position] = rows[i], updates[i]
+					continue
+				case conflictIgnore:
+					continue
+				}
+				return nil, nil, nil, &DuplicateKeyError{Path: documentPath(collectionRef.Doc(docID))}
+			}
+			positions[docID] = len(resultIDs)
+		}
+		resultIDs = append(resultIDs, docID)
+		resultRows = append(resultRows, rows[i])
+		resultUpdates = append(resultUpdates, updates[i])
+	}
+	return resultIDs, resultRows, resultUpdates, nil
+}
+
+// valuesExpr matches VALUES keyword followed by the first row
+var valuesExpr = regexp.MustCompile(`(?i)\bVALUES\s*\(`)
+
+// parseValueRows parses each row of a multi-row VALUES list separately, sqlparser repeats the first row
+func parseValueRows(SQL string, stmt *insert.Statement) error {
+	loc := valuesExpr.FindStringIndex(SQL)
+	if loc == nil {
+		return nil
+	}
+	rows := valueRows(SQL[loc[1]-1:])
+	if len(rows) < 2 {
+		return nil
+	}
+	prefix := SQL[:loc[0]] + "VALUES "
+	stmt.Values = stmt.Values[:0]
+	for _, row := range rows {
+		rowStmt, err := sqlparser.ParseInsert(prefix + row)
+		if err != nil {
+			return err
+		}
+		stmt.Values = append(stmt.Values, rowStmt.Values...)
+	}
+	return nil
+}
+
+// valueRows returns top level parenthesized rows of VALUES list, i.e. (?, 1), ('a', 2)
+func valueRows(text string) []string {
+	var result []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote && text[i-1] != '\\' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				result = append(result, text[start:i+1])
+			}
+		case depth == 0 && c != ',' && c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			return result
+		}
+	}
+	return result
+}
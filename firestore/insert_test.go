@@ -0,0 +1,502 @@
+package firestore
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseInsert(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		SQL            string
+		expectConflict int
+		expectValues   int
+		expectUpdates  []string
+		expectError    bool
+	}{
+		{
+			description:    "plain insert",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?)",
+			expectConflict: conflictError,
+			expectValues:   2,
+		},
+		{
+			description:    "replace into",
+			SQL:            "replace into users (id, name) VALUES (?, ?), (?, ?)",
+			expectConflict: conflictReplace,
+			expectValues:   4,
+		},
+		{
+			description:    "on conflict do nothing",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			expectConflict: conflictIgnore,
+			expectValues:   2,
+		},
+		{
+			description:    "on conflict of document ID do update",
+			SQL:            "INSERT INTO users (id, name, age) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = age + 1",
+			expectConflict: conflictUpdate,
+			expectValues:   3,
+			expectUpdates:  []string{"name", "age"},
+		},
+		{
+			description:    "on duplicate key update",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE name = ?",
+			expectConflict: conflictUpdate,
+			expectValues:   4,
+			expectUpdates:  []string{"name"},
+		},
+		{
+			description: "on conflict of other column",
+			SQL:         "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (name) DO NOTHING",
+			expectError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		insertStmt, err := parseInsert(testCase.SQL)
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if insertStmt.conflict != testCase.expectConflict {
+			t.Errorf("%v: expected conflict %v, but had %v", testCase.description, testCase.expectConflict, insertStmt.conflict)
+		}
+		if len(insertStmt.Values) != testCase.expectValues {
+			t.Errorf("%v: expected %v values, but had %v", testCase.description, testCase.expectValues, len(insertStmt.Values))
+		}
+		var updates []string
+		for _, item := range insertStmt.onConflict {
+			updates = append(updates, sqlparser.Stringify(item.Column))
+		}
+		if !reflect.DeepEqual(updates, testCase.expectUpdates) {
+			t.Errorf("%v: expected updates %v, but had %v", testCase.description, testCase.expectUpdates, updates)
+		}
+	}
+}
+
+func TestStatement_ExecInsertConflict(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		// deleted removes the existing document while the conflict update is committed, the transaction is retried
+		deleted bool
+		// missing is set when no document exists
+		missing        bool
+		expectAffected int64
+		expectDoc      map[string]string
+		expectError    error
+	}{
+		{
+			description: "existing document",
+			SQL:         "INSERT INTO users (id, name) VALUES (?, ?)",
+			args:        []interface{}{"u1", "new"},
+			expectDoc:   map[string]string{"name": "old", "city": "LA"},
+			expectError: ErrDuplicateKey,
+		},
+		{
+			description:    "do nothing",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			args:           []interface{}{"u1", "new"},
+			expectAffected: 0,
+			expectDoc:      map[string]string{"name": "old", "city": "LA"},
+		},
+		{
+			description:    "do update with excluded value",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+			args:           []interface{}{"u1", "new"},
+			expectAffected: 1,
+			expectDoc:      map[string]string{"name": "new", "city": "LA"},
+		},
+		{
+			description:    "on duplicate key update with placeholder",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = ?",
+			args:           []interface{}{"u1", "new", "updated"},
+			expectAffected: 1,
+			expectDoc:      map[string]string{"name": "updated", "city": "LA"},
+		},
+		{
+			description:    "document deleted concurrently is created again",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = 'updated'",
+			args:           []interface{}{"u1", "new"},
+			deleted:        true,
+			expectAffected: 1,
+			expectDoc:      map[string]string{"name": "new"},
+		},
+		{
+			description:    "do update of missing document creates it",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = 'updated'",
+			args:           []interface{}{"u1", "new"},
+			missing:        true,
+			expectAffected: 1,
+			expectDoc:      map[string]string{"name": "new"},
+		},
+		{
+			description:    "replace into",
+			SQL:            "REPLACE INTO users (id, name) VALUES (?, ?)",
+			args:           []interface{}{"u1", "new"},
+			expectAffected: 1,
+			expectDoc:      map[string]string{"name": "new"},
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		if !testCase.missing {
+			fake.put("users/u1", map[string]string{"name": "old", "city": "LA"})
+		}
+		if testCase.deleted {
+			fake.aborts = 1
+			fake.onAbort = func() { delete(fake.docs, fakeDocumentName("users/u1")) }
+		}
+		result, err := db.Exec(testCase.SQL, testCase.args...)
+		if testCase.expectError != nil {
+			if !errors.Is(err, testCase.expectError) {
+				t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+			}
+		} else if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		} else if affected, _ := result.RowsAffected(); affected != testCase.expectAffected {
+			t.Errorf("%v: expected %v rows affected, but had %v", testCase.description, testCase.expectAffected, affected)
+		}
+		if doc := fake.snapshot()["users/u1"]; !reflect.DeepEqual(doc, testCase.expectDoc) {
+			t.Errorf("%v: expected document %v, but had %v", testCase.description, testCase.expectDoc, doc)
+		}
+	}
+}
+
+func TestInsertStatement_ConflictUpdates(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		expect      map[string]interface{}
+		expectError bool
+	}{
+		{
+			description: "excluded value",
+			SQL:         "INSERT INTO t (id, n) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET n = EXCLUDED.n",
+			args:        []interface{}{"d1", 2},
+			expect:      map[string]interface{}{"n": 2},
+		},
+		{
+			description: "excluded value within parenthesis",
+			SQL:         "INSERT INTO t (id, n) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET n = (EXCLUDED.n + 1)",
+			args:        []interface{}{"d1", 2},
+			expect:      map[string]interface{}{"n": int64(3)},
+		},
+		{
+			description: "negated excluded value",
+			SQL:         "INSERT INTO t (id, n) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET n = -EXCLUDED.n",
+			args:        []interface{}{"d1", 2},
+			expect:      map[string]interface{}{"n": int64(-2)},
+		},
+		{
+			description: "excluded value of function argument and placeholder",
+			SQL:         "INSERT INTO t (id, n, name) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET name = UPPER(EXCLUDED.name), n = ?",
+			args:        []interface{}{"d1", 2, "ab", 5},
+			expect:      map[string]interface{}{"name": "AB", "n": 5},
+		},
+		{
+			description: "negated excluded value within expression and quoted minus",
+			SQL:         "INSERT INTO t (id, n, name) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET n = 10 * -EXCLUDED.n, name = '=-x'",
+			args:        []interface{}{"d1", 2, "ab"},
+			expect:      map[string]interface{}{"name": "=-x", "n": int64(-20)},
+		},
+		{
+			description: "negation of excluded value",
+			SQL:         "INSERT INTO t (id, b) VALUES (?, ?) ON DUPLICATE KEY UPDATE b = NOT EXCLUDED.b",
+			args:        []interface{}{"d1", true},
+			expect:      map[string]interface{}{"b": false},
+		},
+		{
+			description: "column reference",
+			SQL:         "INSERT INTO t (id, n) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET n = n * EXCLUDED.n",
+			args:        []interface{}{"d1", 2},
+			expectError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		insertStmt, err := parseInsert(testCase.SQL)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		eval := &evaluator{args: testCase.args}
+		row := map[string]interface{}{}
+		argIndex := 0
+		for _, column := range insertStmt.Columns {
+			if row[column], err = eval.evaluateExprWithArgIndex(&expr.Placeholder{Name: "?"}, &argIndex); err != nil {
+				t.Fatal(err)
+			}
+		}
+		updates, err := insertStmt.conflictUpdates(eval, row, &argIndex)
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		actual := map[string]interface{}{}
+		for _, update := range updates {
+			actual[update.FieldPath[0]] = update.Value
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
+
+func TestValueRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		text        string
+		expect      []string
+	}{
+		{
+			description: "single row",
+			text:        "(?, ?)",
+			expect:      []string{"(?, ?)"},
+		},
+		{
+			description: "multiple rows",
+			text:        "(?, 1), ('a', 2),\n(?, 3)",
+			expect:      []string{"(?, 1)", "('a', 2)", "(?, 3)"},
+		},
+		{
+			description: "nested parentheses",
+			text:        "(?, LOWER(?)), (?, (1 + 2))",
+			expect:      []string{"(?, LOWER(?))", "(?, (1 + 2))"},
+		},
+		{
+			description: "parentheses and commas in strings",
+			text:        `('a), (b', 1), ("c,)", 2), ('it\'s', 3)`,
+			expect:      []string{"('a), (b', 1)", `("c,)", 2)`, `('it\'s', 3)`},
+		},
+		{
+			description: "trailing clause",
+			text:        "(?, ?), (?, ?) RETURNING id",
+			expect:      []string{"(?, ?)", "(?, ?)"},
+		},
+	}
+	for _, testCase := range testCases {
+		actual := valueRows(testCase.text)
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %q, but had %q", testCase.description, testCase.expect, actual)
+		}
+	}
+}
+
+func TestParseValueRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		expect      []string
+	}{
+		{
+			description: "rows of placeholders",
+			SQL:         "INSERT INTO users (id, name) VALUES (?, ?), (?, ?)",
+			expect:      []string{"?", "?", "?", "?"},
+		},
+		{
+			description: "rows of distinct literals",
+			SQL:         "INSERT INTO users (id, name) VALUES ('u1', 'a, b'), ('u2', LOWER('C'))",
+			expect:      []string{"'u1'", "'a, b'", "'u2'", "LOWER('C')"},
+		},
+	}
+	for _, testCase := range testCases {
+		insertStmt, err := parseInsert(testCase.SQL)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []string
+		for _, value := range insertStmt.Values {
+			actual = append(actual, sqlparser.Stringify(value.Expr))
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
+
+func TestInsertStatement_UniqueRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		conflict    int
+		docIDs      []string
+		expectIDs   []string
+		expectRows  []string
+		expectError bool
+	}{
+		{
+			description: "distinct IDs",
+			docIDs:      []string{"a", "b", ""},
+			expectIDs:   []string{"a", "b", ""},
+			expectRows:  []string{"0", "1", "2"},
+		},
+		{
+			description: "auto IDs",
+			docIDs:      []string{"", ""},
+			expectIDs:   []string{"", ""},
+			expectRows:  []string{"0", "1"},
+		},
+		{
+			description: "duplicate ID",
+			docIDs:      []string{"a", "b", "a"},
+			expectError: true,
+		},
+		{
+			description: "replace keeps the last row at the first position",
+			conflict:    conflictReplace,
+			docIDs:      []string{"a", "b", "a"},
+			expectIDs:   []string{"a", "b"},
+			expectRows:  []string{"2", "1"},
+		},
+		{
+			description: "do nothing keeps the first row",
+			conflict:    conflictIgnore,
+			docIDs:      []string{"a", "b", "a"},
+			expectIDs:   []string{"a", "b"},
+			expectRows:  []string{"0", "1"},
+		},
+		{
+			description: "do update reports duplicate ID",
+			conflict:    conflictUpdate,
+			docIDs:      []string{"a", "a"},
+			expectError: true,
+		},
+	}
+	client := newTestClient(t)
+	for _, testCase := range testCases {
+		insertStmt := &insertStatement{conflict: testCase.conflict}
+		rows := make([]map[string]interface{}, len(testCase.docIDs))
+		updates := make([][]firestore.Update, len(testCase.docIDs))
+		for i := range rows {
+			rows[i] = map[string]interface{}{"row": strconv.Itoa(i)}
+		}
+		docIDs, rows, _, err := insertStmt.uniqueRows(client.Collection("users"), testCase.docIDs, rows, updates)
+		if testCase.expectError {
+			if !errors.Is(err, ErrDuplicateKey) {
+				t.Errorf("%v: expected duplicate key error, but had %v", testCase.description, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actualRows []string
+		for _, row := range rows {
+			actualRows = append(actualRows, row["row"].(string))
+		}
+		if !reflect.DeepEqual(docIDs, testCase.expectIDs) || !reflect.DeepEqual(actualRows, testCase.expectRows) {
+			t.Errorf("%v: expected %v %v, but had %v %v", testCase.description, testCase.expectIDs, testCase.expectRows, docIDs, actualRows)
+		}
+	}
+}
+
+func TestStatement_ExecBulkInsert(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		SQL            string
+		args           []interface{}
+		batchErr       error
+		expectAffected int64
+		expectDocs     map[string]map[string]string
+		expectError    error
+		expectFailed   int64
+	}{
+		{
+			description:    "rows",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?), (?, ?)",
+			args:           []interface{}{"u2", "b", "u3", "c"},
+			expectAffected: 2,
+			expectDocs:     map[string]map[string]string{"users/u1": {"name": "old"}, "users/u2": {"name": "b"}, "users/u3": {"name": "c"}},
+		},
+		{
+			description: "duplicate rows fail before any write",
+			SQL:         "INSERT INTO users (id, name) VALUES (?, ?), (?, ?), (?, ?)",
+			args:        []interface{}{"u2", "b", "u3", "c", "u2", "d"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "old"}},
+			expectError: ErrDuplicateKey,
+		},
+		{
+			description:    "replace with duplicate rows writes the last row",
+			SQL:            "REPLACE INTO users (id, name) VALUES (?, ?), (?, ?), (?, ?)",
+			args:           []interface{}{"u1", "a", "u2", "b", "u1", "c"},
+			expectAffected: 2,
+			expectDocs:     map[string]map[string]string{"users/u1": {"name": "c"}, "users/u2": {"name": "b"}},
+		},
+		{
+			description:    "do nothing with duplicate rows writes the first row",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?), (?, ?) ON CONFLICT DO NOTHING",
+			args:           []interface{}{"u2", "b", "u2", "c"},
+			expectAffected: 1,
+			expectDocs:     map[string]map[string]string{"users/u1": {"name": "old"}, "users/u2": {"name": "b"}},
+		},
+		{
+			description:    "do update rows are written one by one",
+			SQL:            "INSERT INTO users (id, name) VALUES (?, ?), (?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name",
+			args:           []interface{}{"u1", "a", "u2", "b"},
+			batchErr:       status.Error(codes.PermissionDenied, "permission denied"),
+			expectAffected: 2,
+			expectDocs:     map[string]map[string]string{"users/u1": {"name": "a"}, "users/u2": {"name": "b"}},
+		},
+		{
+			description:  "failed batch",
+			SQL:          "INSERT INTO users (id, name) VALUES (?, ?), (?, ?)",
+			args:         []interface{}{"u2", "b", "u3", "c"},
+			batchErr:     status.Error(codes.PermissionDenied, "permission denied"),
+			expectDocs:   map[string]map[string]string{"users/u1": {"name": "old"}},
+			expectFailed: 2,
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		fake.put("users/u1", map[string]string{"name": "old"})
+		fake.batchErr = testCase.batchErr
+		result, err := db.Exec(testCase.SQL, testCase.args...)
+		switch {
+		case testCase.expectError != nil:
+			if !errors.Is(err, testCase.expectError) {
+				t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+			}
+		case testCase.expectFailed > 0:
+			bulkErr, ok := err.(*BulkWriteError)
+			if !ok {
+				t.Errorf("%v: expected BulkWriteError, but had %v", testCase.description, err)
+			} else if bulkErr.Failed != testCase.expectFailed || len(bulkErr.Errors) != int(testCase.expectFailed) {
+				t.Errorf("%v: expected %v failed documents, but had %v", testCase.description, testCase.expectFailed, bulkErr.Failed)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		default:
+			if affected, _ := result.RowsAffected(); affected != testCase.expectAffected {
+				t.Errorf("%v: expected %v rows affected, but had %v", testCase.description, testCase.expectAffected, affected)
+			}
+		}
+		if docs := fake.snapshot(); !reflect.DeepEqual(docs, testCase.expectDocs) {
+			t.Errorf("%v: expected documents %v, but had %v", testCase.description, testCase.expectDocs, docs)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package firestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser/node"
+	"google.golang.org/api/iterator"
+)
+
+// queryPlan represents Firestore queries translated from a WHERE clause
+type queryPlan struct {
+	queries []firestore.Query
+	// residual is the bound WHERE clause evaluated on fetched documents, nil if fully translated into query filters
+	residual node.Node
+	// scanLimit is the max number of documents read for residual evaluation, 0 means unlimited
+	scanLimit int
+	// reader reads query documents, nil reads with the query client
+	reader *docReader
+}
+
+// merged returns true if results have to be sorted and windowed client side
+func (p *queryPlan) merged() bool {
+	return len(p.queries) > 1 || p.residual != nil
+}
+
+// fetch iterates documents matched by the plan until fn returns false or an error
+func (p *queryPlan) fetch(ctx context.Context, fn func(doc *firestore.DocumentSnapshot) (bool, error)) error {
+	docs := p.documents(ctx)
+	defer docs.stop()
+	for {
+		doc, err := docs.next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if next, err := fn(doc); err != nil || !next {
+			return err
+		}
+	}
+}
+
+// documents returns an iterator of documents matched by the plan
+func (p *queryPlan) documents(ctx context.Context) *planIterator {
+	result := &planIterator{ctx: ctx, plan: p}
+	if len(p.queries) > 1 {
+		result.seen = make(map[string]bool)
+	}
+	return result
+}
+
+// planIterator iterates documents of plan queries one by one, documents already matched by a previous query are skipped
+type planIterator struct {
+	ctx     context.Context
+	plan    *queryPlan
+	index   int
+	docIter *firestore.DocumentIterator
+	seen    map[string]bool
+	reads   int
+}
+
+// next returns the next matched document or iterator.Done
+func (i *planIterator) next() (*firestore.DocumentSnapshot, error) {
+	for {
+		if err := i.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i.docIter == nil {
+			if i.index >= len(i.plan.queries) {
+				return nil, iterator.Done
+			}
+			if i.plan.reader != nil {
+				i.docIter = i.plan.reader.documents(i.ctx, i.plan.queries[i.index])
+			} else {
+				i.docIter = i.plan.queries[i.index].Documents(i.ctx)
+			}
+			i.index++
+		}
+		doc, err := i.docIter.Next()
+		if err == iterator.Done {
+			i.stop()
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch documents: %w", err)
+		}
+		if i.seen != nil {
+			if i.seen[doc.Ref.Path] {
+				continue
+			}
+			i.seen[doc.Ref.Path] = true
+		}
+		if i.plan.residual != nil {
+			if i.reads++; i.plan.scanLimit > 0 && i.reads > i.plan.scanLimit {
+				return nil, fmt.Errorf("WHERE clause evaluated client side exceeded limit of %v document reads, add conditions Firestore can filter on or raise %v", i.plan.scanLimit, clientScanLimit)
+			}
+			matched, err := i.plan.matches(doc)
+			if err != nil {
+				return nil, err
+			}
+			if !matched {
+				continue
+			}
+		}
+		return doc, nil
+	}
+}
+
+// stop releases the current query iterator
+func (i *planIterator) stop() {
+	if i.docIter != nil {
+		i.docIter.Stop()
+		i.docIter = nil
+	}
+}
+
+// matches evaluates residual WHERE clause on the document
+func (p *queryPlan) matches(doc *firestore.DocumentSnapshot) (bool, error) {
+	matched, err := shared.Matches(p.residual, documentData(doc))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate WHERE clause on document %s: %v", doc.Ref.ID, err)
+	}
+	return matched, nil
+}
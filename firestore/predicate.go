@@ -0,0 +1,647 @@
+package firestore
+
+import (
+	"cloud.google.com/go/firestore"
+	"database/sql/driver"
+	"fmt"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+	"strings"
+)
+
+// comparisonOperators maps SQL comparison operators to Firestore operators
+var comparisonOperators = map[string]string{
+	"=":      "==",
+	"!=":     "!=",
+	">":      ">",
+	">=":     ">=",
+	"<":      "<",
+	"<=":     "<=",
+	"IN":     "in",
+	"NOT IN": "not-in",
+	"IS":     "==",
+}
+
+// arrayFunctions maps SQL array membership functions to Firestore operators
+var arrayFunctions = map[string]string{
+	"ARRAY_CONTAINS":     "array-contains",
+	"ARRAY_CONTAINS_ANY": "array-contains-any",
+}
+
+// flippedOperators maps Firestore operators to their equivalent with swapped operands
+var flippedOperators = map[string]string{
+	">":  "<",
+	">=": "<=",
+	"<":  ">",
+	"<=": ">=",
+}
+
+const (
+	// maxDisjunctions is Firestore's limit of disjunctions in the disjunctive normal form of a query filter
+	maxDisjunctions = 30
+	// maxNotInValues is Firestore's limit of values in a not-in filter
+	maxNotInValues = 10
+	// prefixRangeEnd is appended to a prefix to build the exclusive upper bound of a prefix range scan
+	prefixRangeEnd = "\uf8ff"
+)
+
+// filter represents a single Firestore field condition
+type filter struct {
+	path  string
+	op    string
+	value interface{}
+}
+
+// entity returns Firestore filter
+func (f *filter) entity() firestore.EntityFilter {
+	return firestore.PropertyPathFilter{Path: fieldPath(f.path), Operator: f.op, Value: f.value}
+}
+
+// isArrayMembership returns true for array-contains and array-contains-any filters
+func (f *filter) isArrayMembership() bool {
+	return f.op == "array-contains" || f.op == "array-contains-any"
+}
+
+// isNegation returns true for != and not-in filters
+func (f *filter) isNegation() bool {
+	return f.op == "!=" || f.op == "not-in"
+}
+
+// isMembership returns true for in and array-contains-any filters
+func (f *filter) isMembership() bool {
+	return f.op == "in" || f.op == "array-contains-any"
+}
+
+// disjunctions returns number of disjunctions Firestore counts for the filter
+func (f *filter) disjunctions() int {
+	if f.op == "in" || f.op == "array-contains-any" {
+		if values, ok := f.value.([]interface{}); ok && len(values) > 0 {
+			return len(values)
+		}
+	}
+	return 1
+}
+
+// conjunction represents filters joined by AND
+type conjunction []*filter
+
+// entity returns the conjunction as a single Firestore filter
+func (c conjunction) entity() firestore.EntityFilter {
+	if len(c) == 1 {
+		return c[0].entity()
+	}
+	filters := make([]firestore.EntityFilter, len(c))
+	for i, item := range c {
+		filters[i] = item.entity()
+	}
+	return firestore.AndFilter{Filters: filters}
+}
+
+// disjunctions returns number of disjunctions Firestore counts for the conjunction
+func (c conjunction) disjunctions() int {
+	result := 1
+	for _, item := range c {
+		result *= item.disjunctions()
+	}
+	return result
+}
+
+// validate checks Firestore rule of a single array membership filter per conjunction
+func (c conjunction) validate() error {
+	count := 0
+	for _, item := range c {
+		if item.isArrayMembership() {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("Firestore supports at most one ARRAY_CONTAINS or ARRAY_CONTAINS_ANY condition per query (or OR branch)")
+	}
+	return nil
+}
+
+// split splits the largest IN list of the conjunction into chunks until each part is within Firestore disjunction limit
+func (c conjunction) split() []conjunction {
+	disjunctions := c.disjunctions()
+	if disjunctions <= maxDisjunctions {
+		return []conjunction{c}
+	}
+	largest := 0
+	for i, item := range c {
+		if item.disjunctions() > c[largest].disjunctions() {
+			largest = i
+		}
+	}
+	values := c[largest].value.([]interface{})
+	chunkSize := maxDisjunctions / (disjunctions / len(values))
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	var result []conjunction
+	for i := 0; i < len(values); i += chunkSize {
+		end := i + chunkSize
+		if end > len(values) {
+			end = len(values)
+		}
+		part := make(conjunction, len(c))
+		copy(part, c)
+		part[largest] = &filter{path: c[largest].path, op: c[largest].op, value: values[i:end]}
+		result = append(result, part.split()...)
+	}
+	return result
+}
+
+// predicate translates a WHERE expression into Firestore query filters,
+// collectionRef is nil for collection group
+type predicate struct {
+	collectionRef *firestore.CollectionRef
+	eval          *evaluator
+	argIndex      int
+	residual      bool
+}
+
+// newPredicate creates a predicate translator binding placeholders from args
+func newPredicate(collectionRef *firestore.CollectionRef, args []driver.NamedValue) *predicate {
+	return &predicate{
+		collectionRef: collectionRef,
+		eval:          &evaluator{args: convertNamedValuesToInterfaceSlice(args)},
+	}
+}
+
+// apply returns query plan with filters derived from the WHERE expression,
+// a filter exceeding Firestore disjunction limit (i.e. a long IN list) is split into several queries,
+// results of which have to be merged and deduplicated by document ID.
+// Conditions that cannot be translated into Firestore filters (i.e. LIKE '%infix%') are evaluated on fetched documents
+func (p *predicate) apply(queryRef firestore.Query, n node.Node) (*queryPlan, error) {
+	bound, err := shared.Bind(n, p.eval.args, &p.argIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+	}
+	disjunction, err := p.disjunction(bound)
+	if err != nil {
+		return nil, err
+	}
+	var conjunctions []conjunction
+	for _, conj := range disjunction {
+		if err = conj.validate(); err != nil {
+			return nil, err
+		}
+		conjunctions = append(conjunctions, conj.split()...)
+	}
+	conjunctions = p.withoutNegations(conjunctions)
+	plan := &queryPlan{}
+	if p.residual {
+		plan.residual = parentColumns(bound)
+	}
+	for _, conj := range conjunctions {
+		if len(conj) == 0 {
+			// a branch without server side filters matches any document
+			plan.queries = []firestore.Query{queryRef}
+			return plan, nil
+		}
+	}
+	if len(conjunctions) == 1 {
+		for _, item := range conjunctions[0] {
+			queryRef = queryRef.WhereEntity(item.entity())
+		}
+		plan.queries = []firestore.Query{queryRef}
+		return plan, nil
+	}
+
+	var result []firestore.Query
+	var group []firestore.EntityFilter
+	groupDisjunctions := 0
+	flush := func() {
+		if len(group) == 1 {
+			result = append(result, queryRef.WhereEntity(group[0]))
+		} else if len(group) > 1 {
+			result = append(result, queryRef.WhereEntity(firestore.OrFilter{Filters: group}))
+		}
+		group = nil
+		groupDisjunctions = 0
+	}
+	for _, conj := range conjunctions {
+		if groupDisjunctions+conj.disjunctions() > maxDisjunctions {
+			flush()
+		}
+		group = append(group, conj.entity())
+		groupDisjunctions += conj.disjunctions()
+	}
+	flush()
+	plan.queries = result
+	return plan, nil
+}
+
+// withoutNegations removes != and not-in filters when the WHERE clause has in or array-contains-any filters,
+// which Firestore does not combine in a query; removed filters are evaluated client side
+func (p *predicate) withoutNegations(conjunctions []conjunction) []conjunction {
+	negation, membership := false, false
+	for _, conj := range conjunctions {
+		for _, item := range conj {
+			negation = negation || item.isNegation()
+			membership = membership || item.isMembership()
+		}
+	}
+	if !negation || !membership {
+		return conjunctions
+	}
+	p.residual = true
+	result := make([]conjunction, len(conjunctions))
+	for i, conj := range conjunctions {
+		result[i] = conjunction{}
+		for _, item := range conj {
+			if !item.isNegation() {
+				result[i] = append(result[i], item)
+			}
+		}
+	}
+	return result
+}
+
+// disjunction translates the expression into its disjunctive normal form
+func (p *predicate) disjunction(n node.Node) ([]conjunction, error) {
+	switch actual := shared.Unwrap(n).(type) {
+	case *expr.Binary:
+		switch actual.Op {
+		case "OR":
+			left, err := p.disjunction(actual.X)
+			if err != nil {
+				return nil, err
+			}
+			right, err := p.disjunction(actual.Y)
+			if err != nil {
+				return nil, err
+			}
+			if len(left)+len(right) > maxDisjunctions {
+				return nil, fmt.Errorf("WHERE clause exceeds Firestore limit of %v disjunctions: %s", maxDisjunctions, sqlparser.Stringify(actual))
+			}
+			return append(left, right...), nil
+		case "AND":
+			left, err := p.disjunction(actual.X)
+			if err != nil {
+				return nil, err
+			}
+			right, err := p.disjunction(actual.Y)
+			if err != nil {
+				return nil, err
+			}
+			if len(left)*len(right) > maxDisjunctions {
+				return nil, fmt.Errorf("WHERE clause exceeds Firestore limit of %v disjunctions: %s", maxDisjunctions, sqlparser.Stringify(actual))
+			}
+			var result = make([]conjunction, 0, len(left)*len(right))
+			for _, l := range left {
+				for _, r := range right {
+					conj := make(conjunction, 0, len(l)+len(r))
+					conj = append(append(conj, l...), r...)
+					result = append(result, conj)
+				}
+			}
+			return result, nil
+		}
+		if !isTranslatableComparison(actual) || (p.collectionRef == nil && hasDocIDColumn(actual)) {
+			// collection group documents are identified by path, document ID conditions are evaluated client side
+			return p.residualConjunction(), nil
+		}
+		conj, err := p.comparisonFilters(actual)
+		if err != nil {
+			return nil, err
+		}
+		return []conjunction{conj}, nil
+	case *expr.Call:
+		if !isTranslatableCall(actual) {
+			return p.residualConjunction(), nil
+		}
+		aFilter, err := p.callFilter(actual)
+		if err != nil {
+			return nil, err
+		}
+		return []conjunction{{aFilter}}, nil
+	case nil:
+		return nil, fmt.Errorf("unsupported WHERE clause")
+	default:
+		return p.residualConjunction(), nil
+	}
+}
+
+// residualConjunction marks the predicate as evaluated client side and returns a condition without server side filters
+func (p *predicate) residualConjunction() []conjunction {
+	p.residual = true
+	return []conjunction{{}}
+}
+
+// isTranslatableComparison returns true if comparison can be expressed as Firestore filters,
+// that is a column compared with a constant value
+func isTranslatableComparison(binary *expr.Binary) bool {
+	switch binary.Op {
+	case "LIKE":
+		_, isColumn := columnName(binary.X)
+		return isColumn && isConstant(binary.Y)
+	case "BETWEEN":
+		aRange, ok := binary.Y.(*expr.Range)
+		_, isColumn := columnName(binary.X)
+		return ok && isColumn && isConstant(aRange.Min) && isConstant(aRange.Max)
+	case "=":
+		if call := shared.AnyCall(binary.Y); call != nil {
+			_, isColumn := columnName(call.Args[0])
+			return isColumn && isConstant(binary.X)
+		}
+		if call := shared.AnyCall(binary.X); call != nil {
+			_, isColumn := columnName(call.Args[0])
+			return isColumn && isConstant(binary.Y)
+		}
+	}
+	if _, ok := comparisonOperators[binary.Op]; !ok {
+		return false
+	}
+	if _, isColumn := columnName(binary.X); isColumn {
+		return isConstant(binary.Y)
+	}
+	if _, isColumn := columnName(binary.Y); isColumn && binary.Op != "IN" && binary.Op != "NOT IN" {
+		return isConstant(binary.X)
+	}
+	return false
+}
+
+// hasDocIDColumn returns true if either side of comparison is document ID column
+func hasDocIDColumn(binary *expr.Binary) bool {
+	for _, operand := range []node.Node{binary.X, binary.Y} {
+		if column, ok := columnName(operand); ok && IsDocIDColumn(column) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTranslatableCall returns true for array membership function of a column and constant values
+func isTranslatableCall(call *expr.Call) bool {
+	if _, ok := arrayFunctions[strings.ToUpper(sqlparser.Stringify(call.X))]; !ok || len(call.Args) < 2 {
+		return false
+	}
+	if _, isColumn := columnName(call.Args[0]); !isColumn {
+		return false
+	}
+	for _, arg := range call.Args[1:] {
+		if !isConstant(arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// isConstant returns true for literals, bound placeholders and lists of thereof
+func isConstant(n node.Node) bool {
+	switch actual := n.(type) {
+	case *expr.Literal, *shared.Bound:
+		return true
+	case *expr.Parenthesis:
+		list, ok := actual.X.([]node.Node)
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if !isConstant(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// callFilter translates ARRAY_CONTAINS(column, value) and ARRAY_CONTAINS_ANY(column, values) into a Firestore filter
+func (p *predicate) callFilter(call *expr.Call) (*filter, error) {
+	name := strings.ToUpper(sqlparser.Stringify(call.X))
+	op, ok := arrayFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported function in WHERE clause: %s", sqlparser.Stringify(call))
+	}
+	if len(call.Args) < 2 {
+		return nil, fmt.Errorf("%s expects column and value arguments", name)
+	}
+	column, ok := columnName(call.Args[0])
+	if !ok {
+		return nil, fmt.Errorf("invalid column name in %s", name)
+	}
+	if op == "array-contains" {
+		if len(call.Args) != 2 {
+			return nil, fmt.Errorf("%s expects column and value arguments", name)
+		}
+		value, err := p.eval.evaluateExpr(call.Args[1], &p.argIndex)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+		}
+		return &filter{path: column, op: op, value: value}, nil
+	}
+	var values []interface{}
+	for _, arg := range call.Args[1:] {
+		value, err := p.eval.evaluateExpr(arg, &p.argIndex)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+		}
+		values = append(values, shared.ExpandSlice(value)...)
+	}
+	return &filter{path: column, op: op, value: values}, nil
+}
+
+// anyFilter translates value = ANY(column) into an array-contains filter
+func (p *predicate) anyFilter(binary *expr.Binary) (*filter, bool, error) {
+	if binary.Op != "=" {
+		return nil, false, nil
+	}
+	valueExpr, call := binary.X, shared.AnyCall(binary.Y)
+	if call == nil {
+		valueExpr, call = binary.Y, shared.AnyCall(binary.X)
+	}
+	if call == nil {
+		return nil, false, nil
+	}
+	column, ok := columnName(call.Args[0])
+	if !ok {
+		return nil, false, fmt.Errorf("invalid column name in ANY")
+	}
+	value, err := p.eval.evaluateExpr(valueExpr, &p.argIndex)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+	}
+	return &filter{path: column, op: "array-contains", value: value}, true, nil
+}
+
+// comparisonFilters translates a single comparison into Firestore filters,
+// LIKE 'prefix%' and BETWEEN are translated into range filters
+func (p *predicate) comparisonFilters(binary *expr.Binary) (conjunction, error) {
+	switch binary.Op {
+	case "LIKE":
+		return p.likeFilters(binary)
+	case "BETWEEN":
+		return p.betweenFilters(binary)
+	}
+	aFilter, err := p.comparisonFilter(binary)
+	if err != nil {
+		return nil, err
+	}
+	return conjunction{aFilter}, nil
+}
+
+// comparisonFilter translates a single comparison into a Firestore filter
+func (p *predicate) comparisonFilter(binary *expr.Binary) (*filter, error) {
+	if aFilter, ok, err := p.anyFilter(binary); ok || err != nil {
+		return aFilter, err
+	}
+	column, op, value, err := p.comparison(binary)
+	if err != nil {
+		return nil, err
+	}
+	if op == "not-in" && len(value.([]interface{})) > maxNotInValues {
+		return nil, fmt.Errorf("NOT IN supports at most %v values in Firestore, but had: %v", maxNotInValues, len(value.([]interface{})))
+	}
+	return p.newFilter(column, op, value), nil
+}
+
+// likeFilters translates LIKE 'prefix%' into a prefix range scan,
+// any other pattern is matched on fetched documents
+func (p *predicate) likeFilters(binary *expr.Binary) (conjunction, error) {
+	column, ok := columnName(binary.X)
+	if !ok {
+		return nil, fmt.Errorf("invalid column name in WHERE clause")
+	}
+	value, err := p.eval.evaluateExpr(binary.Y, &p.argIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+	}
+	pattern, ok := value.(string)
+	if !ok {
+		p.residual = true
+		return conjunction{}, nil
+	}
+	prefix, exact, ok := shared.LikePrefix(pattern)
+	if !ok || (prefix == "" && IsDocIDColumn(column)) {
+		p.residual = true
+		return conjunction{}, nil
+	}
+	if exact {
+		return conjunction{p.newFilter(column, "==", prefix)}, nil
+	}
+	return conjunction{
+		p.newFilter(column, ">=", prefix),
+		p.newFilter(column, "<", prefix+prefixRangeEnd),
+	}, nil
+}
+
+// betweenFilters translates BETWEEN into a closed range
+func (p *predicate) betweenFilters(binary *expr.Binary) (conjunction, error) {
+	column, ok := columnName(binary.X)
+	if !ok {
+		return nil, fmt.Errorf("invalid column name in WHERE clause")
+	}
+	aRange, ok := binary.Y.(*expr.Range)
+	if !ok {
+		return nil, fmt.Errorf("invalid BETWEEN range: %s", sqlparser.Stringify(binary))
+	}
+	min, err := p.eval.evaluateExpr(aRange.Min, &p.argIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+	}
+	max, err := p.eval.evaluateExpr(aRange.Max, &p.argIndex)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+	}
+	return conjunction{p.newFilter(column, ">=", min), p.newFilter(column, "<=", max)}, nil
+}
+
+// newFilter creates a filter, document ID values are converted into document references
+func (p *predicate) newFilter(column, op string, value interface{}) *filter {
+	if IsDocIDColumn(column) {
+		return &filter{path: firestore.DocumentID, op: op, value: p.docRefs(value)}
+	}
+	return &filter{path: column, op: op, value: value}
+}
+
+// docRefs converts document ID value(s) into document references
+func (p *predicate) docRefs(value interface{}) interface{} {
+	if values, ok := value.([]interface{}); ok {
+		refs := make([]interface{}, len(values))
+		for i, item := range values {
+			refs[i] = p.collectionRef.Doc(fmt.Sprintf("%v", item))
+		}
+		return refs
+	}
+	return p.collectionRef.Doc(fmt.Sprintf("%v", value))
+}
+
+// comparison resolves column, Firestore operator and value of a comparison,
+// the operator is flipped when the column is on the right side
+func (p *predicate) comparison(binary *expr.Binary) (string, string, interface{}, error) {
+	op, ok := comparisonOperators[binary.Op]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unsupported operator in WHERE clause: %s", binary.Op)
+	}
+	if column, ok := columnName(binary.X); ok {
+		var value interface{}
+		var err error
+		if op == "in" || op == "not-in" {
+			value, err = p.listValues(binary.Y)
+		} else {
+			value, err = p.eval.evaluateExpr(binary.Y, &p.argIndex)
+		}
+		if err != nil {
+			return "", "", nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+		}
+		return column, op, value, nil
+	}
+	if column, ok := columnName(binary.Y); ok && op != "in" && op != "not-in" {
+		value, err := p.eval.evaluateExpr(binary.X, &p.argIndex)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+		}
+		if flipped, ok := flippedOperators[op]; ok {
+			op = flipped
+		}
+		return column, op, value, nil
+	}
+	return "", "", nil, fmt.Errorf("invalid column name in WHERE clause")
+}
+
+// listValues evaluates IN list values, a slice argument bound to a placeholder is expanded into list values
+func (p *predicate) listValues(n node.Node) ([]interface{}, error) {
+	var items []node.Node
+	switch actual := n.(type) {
+	case *expr.Parenthesis:
+		list, ok := actual.X.([]node.Node)
+		if !ok {
+			return nil, fmt.Errorf("invalid IN list: %s", actual.Raw)
+		}
+		items = list
+	default:
+		items = []node.Node{n}
+	}
+	var result = make([]interface{}, 0, len(items))
+	for _, item := range items {
+		value, err := p.eval.evaluateExpr(item, &p.argIndex)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, shared.ExpandSlice(value)...)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("IN list is empty")
+	}
+	return result, nil
+}
+
+// columnName returns column expression of an identifier or a field path node
+func columnName(n node.Node) (string, bool) {
+	switch actual := n.(type) {
+	case *expr.Ident:
+		return actual.Name, true
+	case *expr.Selector:
+		return sqlparser.Stringify(actual), true
+	}
+	return "", false
+}
+
+// fieldPath converts column expression into Firestore field path
+func fieldPath(column string) firestore.FieldPath {
+	return shared.FieldPath(column)
+}
@@ -0,0 +1,400 @@
+package firestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/viant/sqlparser"
+	"google.golang.org/protobuf/proto"
+)
+
+// fieldOperators maps Firestore field filter operators to SQL like text
+var fieldOperators = map[pb.StructuredQuery_FieldFilter_Operator]string{
+	pb.StructuredQuery_FieldFilter_LESS_THAN:             "<",
+	pb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL:    "<=",
+	pb.StructuredQuery_FieldFilter_GREATER_THAN:          ">",
+	pb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL: ">=",
+	pb.StructuredQuery_FieldFilter_EQUAL:                 "==",
+	pb.StructuredQuery_FieldFilter_NOT_EQUAL:             "!=",
+	pb.StructuredQuery_FieldFilter_ARRAY_CONTAINS:        "array-contains",
+	pb.StructuredQuery_FieldFilter_IN:                    "in",
+	pb.StructuredQuery_FieldFilter_ARRAY_CONTAINS_ANY:    "array-contains-any",
+	pb.StructuredQuery_FieldFilter_NOT_IN:                "not-in",
+}
+
+// newTestClient returns a client of a local emulator address, queries are built but never run
+func newTestClient(t *testing.T) *firestore.Client {
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:1")
+	client, err := firestore.NewClient(context.Background(), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// namedValues returns ordinal arguments
+func namedValues(values ...interface{}) []driver.NamedValue {
+	var result []driver.NamedValue
+	for i, value := range values {
+		result = append(result, driver.NamedValue{Ordinal: i + 1, Value: value})
+	}
+	return result
+}
+
+// queryText returns WHERE filter and ORDER BY of a query as text
+func queryText(t *testing.T, queryRef firestore.Query) string {
+	data, err := queryRef.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &pb.RunQueryRequest{}
+	if err = proto.Unmarshal(data, request); err != nil {
+		t.Fatal(err)
+	}
+	structured := request.GetStructuredQuery()
+	result := filterText(structured.GetWhere())
+	for _, order := range structured.GetOrderBy() {
+		result += fmt.Sprintf(" ORDER BY %v %v", order.GetField().GetFieldPath(), order.GetDirection())
+	}
+	if startAt := structured.GetStartAt(); startAt != nil {
+		result += " START AFTER " + valueText(&pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: &pb.ArrayValue{Values: startAt.GetValues()}}})
+	}
+	return strings.TrimSpace(result)
+}
+
+func filterText(aFilter *pb.StructuredQuery_Filter) string {
+	switch actual := aFilter.GetFilterType().(type) {
+	case *pb.StructuredQuery_Filter_CompositeFilter:
+		var items []string
+		for _, item := range actual.CompositeFilter.GetFilters() {
+			items = append(items, filterText(item))
+		}
+		return "(" + strings.Join(items, " "+actual.CompositeFilter.GetOp().String()+" ") + ")"
+	case *pb.StructuredQuery_Filter_FieldFilter:
+		fieldFilter := actual.FieldFilter
+		return fieldFilter.GetField().GetFieldPath() + " " + fieldOperators[fieldFilter.GetOp()] + " " + valueText(fieldFilter.GetValue())
+	case *pb.StructuredQuery_Filter_UnaryFilter:
+		return actual.UnaryFilter.GetField().GetFieldPath() + " " + actual.UnaryFilter.GetOp().String()
+	}
+	return ""
+}
+
+func valueText(value *pb.Value) string {
+	switch actual := value.GetValueType().(type) {
+	case *pb.Value_StringValue:
+		return "'" + actual.StringValue + "'"
+	case *pb.Value_IntegerValue:
+		return fmt.Sprint(actual.IntegerValue)
+	case *pb.Value_DoubleValue:
+		return fmt.Sprint(actual.DoubleValue)
+	case *pb.Value_BooleanValue:
+		return fmt.Sprint(actual.BooleanValue)
+	case *pb.Value_NullValue:
+		return "NULL"
+	case *pb.Value_ReferenceValue:
+		return documentName(actual.ReferenceValue)
+	case *pb.Value_ArrayValue:
+		var items []string
+		for _, item := range actual.ArrayValue.GetValues() {
+			items = append(items, valueText(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return value.String()
+}
+
+// documentName returns document path relative to the database
+func documentName(name string) string {
+	if index := strings.Index(name, "/documents/"); index != -1 {
+		return name[index+len("/documents/"):]
+	}
+	return name
+}
+
+func TestBuildFirestoreSelectQuery(t *testing.T) {
+	var testCases = []struct {
+		description    string
+		SQL            string
+		args           []interface{}
+		expectQueries  []string
+		expectResidual bool
+		expectError    string
+	}{
+		{
+			description:   "AND conjunction",
+			SQL:           "SELECT * FROM t WHERE a = ? AND b > ?",
+			args:          []interface{}{1, 2},
+			expectQueries: []string{"(a == 1 AND b > 2)"},
+		},
+		{
+			description:   "OR disjunction",
+			SQL:           "SELECT * FROM t WHERE a = 1 OR b = 'x'",
+			expectQueries: []string{"(a == 1 OR b == 'x')"},
+		},
+		{
+			description:   "AND binds tighter than OR",
+			SQL:           "SELECT * FROM t WHERE a = 1 OR b = 2 AND c = 3",
+			expectQueries: []string{"(a == 1 OR (b == 2 AND c == 3))"},
+		},
+		{
+			description:   "disjunctive normal form",
+			SQL:           "SELECT * FROM t WHERE (a = 1 OR b = 2) AND c = ?",
+			args:          []interface{}{3},
+			expectQueries: []string{"((a == 1 AND c == 3) OR (b == 2 AND c == 3))"},
+		},
+		{
+			description:   "column on the right side",
+			SQL:           "SELECT * FROM t WHERE 5 < a AND ? >= b",
+			args:          []interface{}{7},
+			expectQueries: []string{"(a > 5 AND b <= 7)"},
+		},
+		{
+			description:   "NOT IN",
+			SQL:           "SELECT * FROM t WHERE a = 1 AND b NOT IN (?)",
+			args:          []interface{}{[]string{"x", "y"}},
+			expectQueries: []string{"(a == 1 AND b not-in ['x', 'y'])"},
+		},
+		{
+			description:    "NOT IN and != combined with IN are evaluated client side",
+			SQL:            "SELECT * FROM t WHERE a IN (1, 2, 3) AND b NOT IN (?) AND c != ?",
+			args:           []interface{}{[]string{"x", "y"}, "z"},
+			expectQueries:  []string{"a in [1, 2, 3]"},
+			expectResidual: true,
+		},
+		{
+			description:    "!= combined with ARRAY_CONTAINS_ANY is evaluated client side",
+			SQL:            "SELECT * FROM t WHERE ARRAY_CONTAINS_ANY(tags, ?) AND c != ?",
+			args:           []interface{}{[]string{"x", "y"}, "z"},
+			expectQueries:  []string{"tags array-contains-any ['x', 'y']"},
+			expectResidual: true,
+		},
+		{
+			description:    "!= of an OR branch combined with IN scans the collection",
+			SQL:            "SELECT * FROM t WHERE a IN (1, 2) OR c != 'z'",
+			expectQueries:  []string{""},
+			expectResidual: true,
+		},
+		{
+			description:   "IN list exceeding disjunction limit is split",
+			SQL:           "SELECT * FROM t WHERE a IN (?)",
+			args:          []interface{}{intValues(0, 35)},
+			expectQueries: []string{"a in " + valuesText(intValues(0, 30)), "a in " + valuesText(intValues(30, 35))},
+		},
+		{
+			description:   "LIKE prefix is a range scan",
+			SQL:           "SELECT * FROM t WHERE name LIKE ?",
+			args:          []interface{}{"ab%"},
+			expectQueries: []string{"(name >= 'ab' AND name < 'ab\uf8ff')"},
+		},
+		{
+			description:   "LIKE without wildcards is an equality",
+			SQL:           "SELECT * FROM t WHERE name LIKE 'ab'",
+			expectQueries: []string{"name == 'ab'"},
+		},
+		{
+			description:    "LIKE infix is matched client side",
+			SQL:            "SELECT * FROM t WHERE name LIKE '%ab%' AND a = 1",
+			expectQueries:  []string{"a == 1"},
+			expectResidual: true,
+		},
+		{
+			description:   "BETWEEN is a closed range",
+			SQL:           "SELECT * FROM t WHERE a BETWEEN ? AND ?",
+			args:          []interface{}{1, 5},
+			expectQueries: []string{"(a >= 1 AND a <= 5)"},
+		},
+		{
+			description:    "untranslatable condition of a conjunction is evaluated client side",
+			SQL:            "SELECT * FROM t WHERE a = ? AND LOWER(name) = ? AND b > ?",
+			args:           []interface{}{1, "x", 2},
+			expectQueries:  []string{"(a == 1 AND b > 2)"},
+			expectResidual: true,
+		},
+		{
+			description:    "untranslatable OR branch scans the collection",
+			SQL:            "SELECT * FROM t WHERE a = 1 OR b = c",
+			expectQueries:  []string{""},
+			expectResidual: true,
+		},
+		{
+			description: "NOT IN exceeding Firestore limit",
+			SQL:         "SELECT * FROM t WHERE a NOT IN (?)",
+			args:        []interface{}{intValues(0, 11)},
+			expectError: "NOT IN supports at most 10 values in Firestore, but had: 11",
+		},
+		{
+			description: "disjunctions exceeding Firestore limit",
+			SQL:         "SELECT * FROM t WHERE (a = 1 OR b = 1 OR c = 1 OR d = 1 OR e = 1 OR f = 1) AND (g = 1 OR h = 1 OR i = 1 OR j = 1 OR k = 1 OR l = 1)",
+			expectError: "WHERE clause exceeds Firestore limit of 30 disjunctions",
+		},
+	}
+	client := newTestClient(t)
+	for _, testCase := range testCases {
+		selectStmt, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.description, err)
+		}
+		plan, _, err := buildFirestoreSelectQuery(newCollection(client, client.Collection("t")), selectStmt, namedValues(testCase.args...))
+		if testCase.expectError != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.expectError) {
+				t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var queries []string
+		for _, queryRef := range plan.queries {
+			queries = append(queries, queryText(t, queryRef))
+		}
+		if !reflect.DeepEqual(queries, testCase.expectQueries) {
+			t.Errorf("%v: expected queries %q, but had %q", testCase.description, testCase.expectQueries, queries)
+		}
+		if residual := plan.residual != nil; residual != testCase.expectResidual {
+			t.Errorf("%v: expected residual %v, but had %v", testCase.description, testCase.expectResidual, residual)
+		}
+	}
+}
+
+func TestConjunction_Split(t *testing.T) {
+	var testCases = []struct {
+		description        string
+		conj               conjunction
+		expectDisjunctions []int
+	}{
+		{
+			description:        "within limit",
+			conj:               conjunction{{path: "a", op: "in", value: intValues(0, 30)}},
+			expectDisjunctions: []int{30},
+		},
+		{
+			description:        "single IN list",
+			conj:               conjunction{{path: "a", op: "in", value: intValues(0, 65)}},
+			expectDisjunctions: []int{30, 30, 5},
+		},
+		{
+			description:        "largest of two IN lists",
+			conj:               conjunction{{path: "a", op: "in", value: intValues(0, 2)}, {path: "b", op: "in", value: intValues(0, 65)}},
+			expectDisjunctions: []int{30, 30, 30, 30, 10},
+		},
+		{
+			description:        "product of two IN lists",
+			conj:               conjunction{{path: "a", op: "in", value: intValues(0, 6)}, {path: "b", op: "in", value: intValues(0, 10)}},
+			expectDisjunctions: []int{30, 30},
+		},
+	}
+	for _, testCase := range testCases {
+		parts := testCase.conj.split()
+		var disjunctions []int
+		combinations := 0
+		for _, part := range parts {
+			if part.disjunctions() > maxDisjunctions {
+				t.Errorf("%v: part exceeds disjunction limit: %v", testCase.description, part.disjunctions())
+			}
+			disjunctions = append(disjunctions, part.disjunctions())
+			combinations += part.disjunctions()
+		}
+		if !reflect.DeepEqual(disjunctions, testCase.expectDisjunctions) {
+			t.Errorf("%v: expected disjunctions %v, but had %v", testCase.description, testCase.expectDisjunctions, disjunctions)
+		}
+		if combinations != testCase.conj.disjunctions() {
+			t.Errorf("%v: expected %v combinations, but had %v", testCase.description, testCase.conj.disjunctions(), combinations)
+		}
+	}
+}
+
+// intValues returns int64 values of [from, to) range
+func intValues(from, to int) []interface{} {
+	var result []interface{}
+	for i := from; i < to; i++ {
+		result = append(result, int64(i))
+	}
+	return result
+}
+
+// valuesText returns values as query text list
+func valuesText(values []interface{}) string {
+	var items []string
+	for _, value := range values {
+		items = append(items, fmt.Sprint(value))
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func TestStatement_QueryResidual(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		// expectFilters are filters received by the server
+		expectFilters []string
+		expectIDs     []string
+	}{
+		{
+			description:   "function call of a conjunction",
+			SQL:           "SELECT id FROM users WHERE name = 'a' AND LOWER(city) = 'la'",
+			expectFilters: []string{"name == 'a'"},
+			expectIDs:     []string{"u1"},
+		},
+		{
+			description:   "cross-field comparison",
+			SQL:           "SELECT id FROM users WHERE name = nick",
+			expectFilters: []string{""},
+			expectIDs:     []string{"u4"},
+		},
+		{
+			description:   "OR branch evaluated client side scans the collection",
+			SQL:           "SELECT id FROM users WHERE city = 'SF' OR LOWER(name) = 'b'",
+			expectFilters: []string{""},
+			expectIDs:     []string{"u2", "u3"},
+		},
+		{
+			description:   "function call combined with OR of server filters",
+			SQL:           "SELECT id FROM users WHERE (name = 'a' OR name = 'b') AND LOWER(city) = 'la'",
+			expectFilters: []string{"(name == 'a' OR name == 'b')"},
+			expectIDs:     []string{"u1", "u2"},
+		},
+		{
+			description:   "OR of a server filter and a partially client side branch",
+			SQL:           "SELECT id FROM users WHERE name = 'c' OR (name = 'a' AND LOWER(city) = 'sf')",
+			expectFilters: []string{"(name == 'c' OR name == 'a')"},
+			expectIDs:     []string{"u3", "u4"},
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1", map[string]string{"name": "a", "city": "LA"})
+	fake.put("users/u2", map[string]string{"name": "b", "city": "la"})
+	fake.put("users/u3", map[string]string{"name": "a", "city": "SF"})
+	fake.put("users/u4", map[string]string{"name": "c", "nick": "c", "city": "NY"})
+	for _, testCase := range testCases {
+		fake.filters = nil
+		rows, err := db.Query(testCase.SQL)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err = rows.Scan(&id); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(fake.filters, testCase.expectFilters) {
+			t.Errorf("%v: expected filters %q, but had %q", testCase.description, testCase.expectFilters, fake.filters)
+		}
+		if !reflect.DeepEqual(ids, testCase.expectIDs) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expectIDs, ids)
+		}
+	}
+}
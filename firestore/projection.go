@@ -0,0 +1,71 @@
+package firestore
+
+import (
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser/node"
+	"github.com/viant/sqlparser/query"
+)
+
+// projection returns field paths read by a select statement: select list, ORDER BY and client side evaluated WHERE clause columns,
+// false is returned if whole documents are needed, i.e. SELECT *
+func projection(selectStmt *query.Select, residual node.Node) ([]firestore.FieldPath, bool) {
+	if len(selectStmt.List) == 0 || selectStmt.List.IsStarExpr() {
+		return nil, false
+	}
+	var nodes []node.Node
+	for _, item := range selectStmt.List {
+		if isCursorColumn(item.Expr) || isParentColumn(item.Expr) {
+			continue
+		}
+		nodes = append(nodes, item.Expr)
+	}
+	for _, item := range selectStmt.OrderBy {
+		nodes = append(nodes, item.Expr)
+	}
+	nodes = append(nodes, residual)
+
+	var columns []string
+	for _, n := range nodes {
+		referenced, ok := shared.Columns(n)
+		if !ok {
+			return nil, false
+		}
+		columns = append(columns, referenced...)
+	}
+
+	// document ID is always returned, nested paths of a selected field are redundant
+	var selected = make(map[string]bool)
+	for _, column := range columns {
+		if !IsDocIDColumn(column) && column != parentKey {
+			selected[strings.Join(shared.FieldPath(column), pathSeparator)] = true
+		}
+	}
+	var result []firestore.FieldPath
+	var added = make(map[string]bool)
+	for _, column := range columns {
+		path := shared.FieldPath(column)
+		key := strings.Join(path, pathSeparator)
+		if !selected[key] || added[key] || hasSelectedParent(selected, path) {
+			continue
+		}
+		added[key] = true
+		result = append(result, path)
+	}
+	return result, true
+}
+
+// pathSeparator joins field path segments into a key, segments can contain dots
+const pathSeparator = "\x00"
+
+// hasSelectedParent returns true if a parent field of the field path is selected
+func hasSelectedParent(selected map[string]bool, path []string) bool {
+	for i := 1; i < len(path); i++ {
+		if selected[strings.Join(path[:i], pathSeparator)] {
+			return true
+		}
+	}
+	return false
+}
@@ -10,11 +10,23 @@ import (
 	"github.com/viant/sqlparser/expr"
 	"github.com/viant/sqlparser/node"
 	"github.com/viant/sqlparser/query"
+	"sort"
+	"strings"
 )
 
 // Implementation of select operation
 func (s *Statement) querySelect(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	selectStmt, err := sqlparser.ParseQuery(s.SQL)
+	// AS OF TIMESTAMP clause or readTime setting reads documents as they were at the time
+	SQL, readTime, args, err := asOfReadTime(s.SQL, args)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := s.selectReader(readTime)
+	if err != nil {
+		return nil, err
+	}
+
+	selectStmt, err := sqlparser.ParseQuery(SQL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse select statement: %v", err)
 	}
@@ -27,36 +39,26 @@ func (s *Statement) querySelect(ctx context.Context, args []driver.NamedValue) (
 		}
 	}
 
-	// Get collection reference - check if it's a subcollection path
-	var collectionRef *firestore.CollectionRef
-
-	// Check for collection[id=?].subcollection format
-	selector := sqlparser.TableSelector(selectStmt)
-	if selector != nil && selector.Expression != "" {
-
-		if shared.IsDryRun(selectStmt) {
-			collectionRef := s.conn.client.Collection(selector.Name)
-			docIter := collectionRef.Documents(ctx)
-			defer docIter.Stop()
-			if doc, _ := docIter.Next(); doc != nil {
-				args[0].Value = doc.Ref.ID
-			}
-		}
-
-		// Handle collection[id=?].subcollection format
-		parent, subCollection, err := parseDocumentPath(selector, args)
+	// Get collection reference - check if it's a collection group or a subcollection path
+	var coll *collection
+	if collectionID, ok := collectionGroupID(tableName(selectStmt)); ok {
+		// Collection group, i.e. group:orders, queries all collections with the same ID
+		coll = newCollectionGroup(s.conn.client, collectionID)
+	} else {
+		// Collection, slash path or collection[id=?].subcollection format, WHERE clause arguments follow document IDs
+		collectionRef, whereArgs, err := s.collectionReference(ctx, selectStmt, args, shared.IsDryRun(selectStmt))
 		if err != nil {
 			return nil, err
 		}
+		coll, args = newCollection(s.conn.client, collectionRef), whereArgs
+	}
 
-		collectionRef = s.conn.client.Collection(parent).Doc(subCollection.docID).Collection(subCollection.collName)
-	} else {
-		// Standard collection reference
-		collectionName := sqlparser.TableName(selectStmt)
-		collectionRef = s.conn.client.Collection(collectionName)
+	// Compute aggregates with Firestore aggregation query or client side
+	if shared.IsAggregate(selectStmt) {
+		return s.queryAggregate(ctx, reader, coll, selectStmt, args)
 	}
 
-	// Check if we have a WHERE clause with docid = 'value'
+	// Check if we have a WHERE clause with docid = 'value', collection group documents are identified by path
 	argsInterface := convertNamedValuesToInterfaceSlice(args)
 	docID, hasDocID, err := FindDocIDInWhere(selectStmt.Qualify, argsInterface)
 	if err != nil {
@@ -64,170 +66,298 @@ func (s *Statement) querySelect(ctx context.Context, args []driver.NamedValue) (
 	}
 
 	// If we have a docid filter, fetch the document directly
-	if hasDocID {
-		docRef := collectionRef.Doc(docID)
-		doc, err := docRef.Get(ctx)
+	if hasDocID && !coll.isGroup() {
+		docRef := coll.ref.Doc(docID)
+		doc, err := reader.get(ctx, docRef)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get document by ID: %v", err)
 		}
 
-		// Create result from the single document
+		// Create result from the single document, including the document ID as a field
 		var results []map[string]interface{}
-		data := doc.Data()
-
-		// Include the document ID as a field
-		data[DocIDColumn] = doc.Ref.ID
-
-		results = append(results, data)
+		results = append(results, documentData(doc))
+		if err = addCursors(coll, selectStmt, results); err != nil {
+			return nil, err
+		}
 
 		// Build Rows from results
-		rows := NewRows(results, false, selectStmt)
-		return rows, nil
+		return NewRows(results, false, selectStmt)
 	}
 
-	// Build the query based on WHERE clause
-	queryRef, dryRun, err := buildFirestoreSelectQuery(collectionRef, selectStmt, args)
+	// If we have a docid IN (...) filter, fetch documents in a batch
+	docIDs, hasDocIDs, err := FindDocIDsInWhere(selectStmt.Qualify, argsInterface)
 	if err != nil {
 		return nil, err
 	}
+	if hasDocIDs && !coll.isGroup() {
+		results, err := getDocuments(ctx, reader, coll.ref, docIDs, s.conn.cfg.NullMissingDocuments)
+		if err != nil {
+			return nil, err
+		}
+		sortResults(results, selectStmt.OrderBy)
+		limit, offset, err := queryWindow(selectStmt)
+		if err != nil {
+			return nil, err
+		}
+		results = windowResults(results, limit, offset)
+		if err = addCursors(coll, selectStmt, results); err != nil {
+			return nil, err
+		}
+		AddDocIDToResults(selectStmt, results)
+		return NewRows(results, false, selectStmt)
+	}
 
-	// Fetch documents
-	docIter := queryRef.Documents(ctx)
-	defer docIter.Stop()
+	// Build the queries based on WHERE clause
+	plan, dryRun, err := buildFirestoreSelectQuery(coll, selectStmt, args)
+	if err != nil {
+		return nil, err
+	}
+	plan.scanLimit = s.conn.cfg.ClientScanLimit
+	plan.reader = reader
+	limit, offset, err := queryWindow(selectStmt)
+	if err != nil {
+		return nil, err
+	}
 
-	// Collect results
-	var results []map[string]interface{}
+	// Fetch only fields the statement reads
+	if fieldPaths, ok := projection(selectStmt, plan.residual); ok {
+		for i, queryRef := range plan.queries {
+			plan.queries[i] = queryRef.SelectPaths(fieldPaths...)
+		}
+	}
 
-	for {
-		doc, err := docIter.Next()
-		if err != nil {
-			break
+	// Stream documents of a single query, already ordered by Firestore; rows read within a transaction are buffered
+	if len(plan.queries) == 1 && !dryRun && s.conn.tx == nil {
+		streamOffset, streamLimit := 0, 0
+		if plan.residual != nil {
+			// LIMIT and OFFSET are applied once documents are filtered client side
+			streamOffset, streamLimit = offset, limit
 		}
+		return newStreamRows(plan.documents(ctx), selectStmt, func(doc *firestore.DocumentSnapshot) (map[string]interface{}, error) {
+			results := []map[string]interface{}{documentData(doc)}
+			if err := addCursors(coll, selectStmt, results); err != nil {
+				return nil, err
+			}
+			AddDocIDToResults(selectStmt, results)
+			return results[0], nil
+		}, streamOffset, streamLimit)
+	}
 
-		data := doc.Data()
+	// Collect results of split queries to merge them
+	var results []map[string]interface{}
 
+	err = plan.fetch(ctx, func(doc *firestore.DocumentSnapshot) (bool, error) {
 		// Include the document ID as a field
-		data[DocIDColumn] = doc.Ref.ID
+		results = append(results, documentData(doc))
+		return !dryRun, nil //just fetch one record
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		results = append(results, data)
-		if dryRun { //just fetch one record
-			break
-		}
+	// Merge results of split queries or client side filtered results
+	if plan.merged() {
+		sortResults(results, selectStmt.OrderBy)
+		results = windowResults(results, limit, offset)
+	}
+
+	// Add continuation cursors if CURSOR() is selected
+	if err = addCursors(coll, selectStmt, results); err != nil {
+		return nil, err
 	}
 
 	// Add docid to results if needed
 	AddDocIDToResults(selectStmt, results)
 
 	// Build Rows from results
-	rows := NewRows(results, dryRun, selectStmt)
+	return NewRows(results, dryRun, selectStmt)
+}
 
-	return rows, nil
+// getDocuments fetches documents by IDs in a single batch, results follow IDs order,
+// missing documents are omitted unless nullMissing is set, in which case only their ID is returned
+func getDocuments(ctx context.Context, reader *docReader, collectionRef *firestore.CollectionRef, docIDs []string, nullMissing bool) ([]map[string]interface{}, error) {
+	docRefs := make([]*firestore.DocumentRef, 0, len(docIDs))
+	for _, docID := range docIDs {
+		docRefs = append(docRefs, collectionRef.Doc(docID))
+	}
+	if len(docRefs) == 0 {
+		return nil, nil
+	}
+	docs, err := reader.getAll(ctx, docRefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents by IDs: %v", err)
+	}
+	var results = make([]map[string]interface{}, 0, len(docs))
+	for i, doc := range docs {
+		if !doc.Exists() {
+			if nullMissing {
+				results = append(results, map[string]interface{}{DocIDColumn: docRefs[i].ID})
+			}
+			continue
+		}
+		results = append(results, documentData(doc))
+	}
+	return results, nil
 }
 
-// Helper function to build Firestore query from SELECT statement
-func buildFirestoreSelectQuery(collectionRef *firestore.CollectionRef, selectStmt *query.Select, args []driver.NamedValue) (firestore.Query, bool, error) {
-	eval := &evaluator{args: convertNamedValuesToInterfaceSlice(args)}
-	queryRef := collectionRef.Query
+// Helper function to build Firestore query plan from SELECT statement,
+// more than one query is planned when the WHERE clause has to be split to meet Firestore limits
+func buildFirestoreSelectQuery(coll *collection, selectStmt *query.Select, args []driver.NamedValue) (*queryPlan, bool, error) {
+	plan := &queryPlan{queries: []firestore.Query{coll.query}}
+	var aCursor *cursor
 
-	argIndex := 0
 	// Apply WHERE clause
 	if selectStmt.Qualify != nil && selectStmt.Qualify.X != nil {
-		// Skip processing if the WHERE clause is just docid = value (already handled)
-		isDocIDOnly := false
-
-		if binary, ok := selectStmt.Qualify.X.(*expr.Binary); ok {
-			if binary.Op == "=" {
-				if ident, ok := binary.X.(*expr.Ident); ok && IsDocIDColumn(ident.Name) {
-					isDocIDOnly = true
-				}
+		if binary, ok := selectStmt.Qualify.X.(*expr.Binary); ok && shared.IsFalsePredicate(binary) {
+			return plan, true, nil
+		}
+		argIndex := 0
+		where, err := shared.Bind(shared.Normalize(selectStmt.Qualify.X), convertNamedValuesToInterfaceSlice(args), &argIndex)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
+		}
+		if where, aCursor, err = extractCursor(where, selectStmt.OrderBy); err != nil {
+			return nil, false, err
+		}
+		if where != nil {
+			if plan, err = newPredicate(coll.ref, args).apply(coll.query, where); err != nil {
+				return nil, false, err
 			}
 		}
+	}
 
-		if !isDocIDOnly {
-			switch amExpr := selectStmt.Qualify.X.(type) {
-			case *expr.Binary:
-
-				if shared.IsFalsePredicate(amExpr) {
-					return queryRef, true, nil
-				}
-
-				colName, ok := amExpr.X.(*expr.Ident)
-				if !ok {
-					return queryRef, false, fmt.Errorf("invalid column name in WHERE clause")
-				}
-
-				// Skip docid condition as it's handled separately
-				if IsDocIDColumn(colName.Name) {
-					break
-				}
-
-				value, err := eval.evaluateExpr(amExpr.Y, &argIndex)
-				if err != nil {
-					return queryRef, false, fmt.Errorf("could not resolve value in WHERE clause: %v", err)
-				}
-				switch amExpr.Op {
-				case "=":
-					queryRef = queryRef.Where(colName.Name, "==", value)
-				case ">", ">=", "<", "<=":
-					queryRef = queryRef.Where(colName.Name, amExpr.Op, value)
-				default:
-					return queryRef, false, fmt.Errorf("unsupported operator in WHERE clause: %s", amExpr.Op)
-				}
-			default:
-				return queryRef, false, fmt.Errorf("unsupported WHERE clause")
+	limit, offset, err := queryWindow(selectStmt)
+	if err != nil {
+		return nil, false, err
+	}
+	queries := plan.queries
+	for i, queryRef := range queries {
+		// Apply ORDER BY
+		if queryRef, err = applyOrderBy(queryRef, selectStmt.OrderBy); err != nil {
+			return nil, false, err
+		}
+		// Resume after continuation cursor
+		if aCursor != nil {
+			queryRef = applyCursor(coll, queryRef, selectStmt.OrderBy, aCursor)
+		}
+		if plan.residual != nil {
+			// LIMIT and OFFSET are applied once documents are filtered client side
+			queries[i] = queryRef
+			continue
+		}
+		if len(queries) > 1 {
+			// OFFSET is applied once results of all queries are merged
+			if limit > 0 {
+				queryRef = queryRef.Limit(limit + offset)
 			}
+			queries[i] = queryRef
+			continue
 		}
+
+		// Apply LIMIT
+		if limit > 0 {
+			queryRef = queryRef.Limit(limit)
+		}
+
+		// Apply OFFSET
+		if offset > 0 {
+			queryRef = queryRef.Offset(offset)
+		}
+		queries[i] = queryRef
+	}
+	return plan, false, nil
+}
+
+// applyOrderBy applies ORDER BY clause to the query
+func applyOrderBy(queryRef firestore.Query, orderBy query.List) (firestore.Query, error) {
+	for _, item := range orderBy {
+		if isParentColumn(item.Expr) {
+			return queryRef, fmt.Errorf("%v() is not supported in ORDER BY", parentFunction)
+		}
+		column, ok := columnName(item.Expr)
+		if !ok {
+			return queryRef, fmt.Errorf("unsupported ORDER BY expression")
+		}
+		direction := firestore.Asc
+		if strings.EqualFold(item.Direction, "DESC") {
+			direction = firestore.Desc
+		}
+		// Document ID column orders by document key
+		if IsDocIDColumn(column) {
+			queryRef = queryRef.OrderBy(firestore.DocumentID, direction)
+			continue
+		}
+		queryRef = queryRef.OrderByPath(fieldPath(column), direction)
 	}
+	return queryRef, nil
+}
 
-	// Apply LIMIT
+// queryWindow returns LIMIT and OFFSET values of SELECT statement, zero is returned for absent value
+func queryWindow(selectStmt *query.Select) (limit int, offset int, err error) {
 	if selectStmt.Limit != nil {
 		limitValue, err := parseExpressionValue(selectStmt.Limit)
 		if err != nil {
-			return queryRef, false, fmt.Errorf("failed to parse LIMIT value: %v", err)
+			return 0, 0, fmt.Errorf("failed to parse LIMIT value: %v", err)
 		}
 		limitInt, ok := limitValue.(int64)
 		if !ok {
-			return queryRef, false, fmt.Errorf("LIMIT value is not an integer")
+			return 0, 0, fmt.Errorf("LIMIT value is not an integer")
 		}
-		queryRef = queryRef.Limit(int(limitInt))
+		limit = int(limitInt)
 	}
-
-	// Apply OFFSET
 	if selectStmt.Offset != nil {
 		offsetValue, err := parseExpressionValue(selectStmt.Offset)
 		if err != nil {
-			return queryRef, false, fmt.Errorf("failed to parse OFFSET value: %v", err)
+			return 0, 0, fmt.Errorf("failed to parse OFFSET value: %v", err)
 		}
 		offsetInt, ok := offsetValue.(int64)
 		if !ok {
-			return queryRef, false, fmt.Errorf("OFFSET value is not an integer")
+			return 0, 0, fmt.Errorf("OFFSET value is not an integer")
 		}
-		queryRef = queryRef.Offset(int(offsetInt))
+		offset = int(offsetInt)
 	}
+	return limit, offset, nil
+}
 
-	// Apply ORDER BY
-	if len(selectStmt.OrderBy) > 0 {
-		for _, item := range selectStmt.OrderBy {
-			colExpr := item.Expr
-			colName, ok := colExpr.(*expr.Ident)
-			if !ok {
-				return queryRef, false, fmt.Errorf("unsupported ORDER BY expression")
-			}
-			// Skip docid order by as it's not supported directly
-			if IsDocIDColumn(colName.Name) {
+// sortResults sorts results by ORDER BY clause, ties are ordered by document ID the same way Firestore does
+func sortResults(results []map[string]interface{}, orderBy query.List) {
+	if len(orderBy) == 0 {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		descending := false
+		for _, item := range orderBy {
+			column := sqlparser.Stringify(item.Expr)
+			descending = strings.EqualFold(item.Direction, "DESC")
+			diff := shared.Compare(shared.Lookup(results[i], column), shared.Lookup(results[j], column))
+			if diff == 0 {
 				continue
 			}
-			direction := firestore.Asc
-			if item.Direction != "" {
-				if item.Direction == "DESC" {
-					direction = firestore.Desc
-				}
+			if descending {
+				return diff > 0
 			}
-			queryRef = queryRef.OrderBy(colName.Name, direction)
+			return diff < 0
 		}
-	}
+		diff := shared.Compare(results[i][DocIDColumn], results[j][DocIDColumn])
+		if descending {
+			return diff > 0
+		}
+		return diff < 0
+	})
+}
 
-	return queryRef, false, nil
+// windowResults applies LIMIT and OFFSET to results
+func windowResults(results []map[string]interface{}, limit, offset int) []map[string]interface{} {
+	if offset > 0 {
+		if offset >= len(results) {
+			return nil
+		}
+		results = results[offset:]
+	}
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results
 }
 
 // Helper function to parse expressions to values
@@ -0,0 +1,306 @@
+package firestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStatement_QueryColumns(t *testing.T) {
+	var testCases = []struct {
+		description   string
+		SQL           string
+		expectColumns []string
+		expectRows    int
+	}{
+		{
+			description:   "star columns are the union of fields of all documents",
+			SQL:           "SELECT * FROM users",
+			expectColumns: []string{"id", "city", "name", "zip"},
+			expectRows:    3,
+		},
+		{
+			description:   "select list",
+			SQL:           "SELECT id, zip FROM users",
+			expectColumns: []string{"id", "zip"},
+			expectRows:    3,
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1", map[string]string{"name": "a"})
+	fake.put("users/u2", map[string]string{"name": "b", "city": "LA"})
+	fake.put("users/u3", map[string]string{"zip": "90001"})
+	for _, testCase := range testCases {
+		rows, err := db.Query(testCase.SQL)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+		}
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		rows.Close()
+		if !reflect.DeepEqual(columns, testCase.expectColumns) || count != testCase.expectRows {
+			t.Errorf("%v: expected %v of %v rows, but had %v of %v rows", testCase.description, testCase.expectColumns, testCase.expectRows, columns, count)
+		}
+	}
+}
+
+func TestStatement_QueryStarStream(t *testing.T) {
+	var testCases = []struct {
+		description   string
+		SQL           string
+		expectColumns []string
+		// expectBuffered is the number of documents read ahead to determine columns
+		expectBuffered int
+		expectRows     int
+	}{
+		{
+			description:    "fields of documents after the sample are not columns",
+			SQL:            "SELECT * FROM users",
+			expectColumns:  []string{"id", "city", "name"},
+			expectBuffered: starSampleSize,
+			expectRows:     starSampleSize + 50,
+		},
+		{
+			description:    "limit within the sample",
+			SQL:            "SELECT * FROM users LIMIT 10",
+			expectColumns:  []string{"id", "name"},
+			expectBuffered: 10,
+			expectRows:     10,
+		},
+		{
+			description:    "select list",
+			SQL:            "SELECT id, zip FROM users",
+			expectColumns:  []string{"id", "zip"},
+			expectBuffered: 1,
+			expectRows:     starSampleSize + 50,
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	for i := 0; i < starSampleSize+50; i++ {
+		fields := map[string]string{"name": fmt.Sprintf("n%03d", i)}
+		switch i {
+		case 50:
+			fields["city"] = "LA"
+		case starSampleSize + 10:
+			fields["zip"] = "90001"
+		}
+		fake.put(fmt.Sprintf("users/u%03d", i), fields)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, testCase := range testCases {
+		err = conn.Raw(func(driverConn interface{}) error {
+			driverRows, err := driverConn.(*connection).QueryContext(ctx, testCase.SQL, nil)
+			if err != nil {
+				return err
+			}
+			rows := driverRows.(*Rows)
+			defer rows.Close()
+			if rows.stream == nil {
+				return fmt.Errorf("expected streamed rows")
+			}
+			if buffered := len(rows.stream.buffered); buffered != testCase.expectBuffered {
+				return fmt.Errorf("expected %v documents read ahead, but had %v", testCase.expectBuffered, buffered)
+			}
+			if columns := rows.Columns(); !reflect.DeepEqual(columns, testCase.expectColumns) {
+				return fmt.Errorf("expected columns %v, but had %v", testCase.expectColumns, columns)
+			}
+			dest := make([]driver.Value, len(testCase.expectColumns))
+			count := 0
+			for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+				count++
+			}
+			if err != io.EOF {
+				return err
+			}
+			if count != testCase.expectRows {
+				return fmt.Errorf("expected %v rows, but had %v", testCase.expectRows, count)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+		}
+	}
+}
+
+func TestStatement_QueryFieldPaths(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		// expectQuery is the filter and ordering received by the server
+		expectQuery string
+		expect      []string
+	}{
+		{
+			description: "nested field",
+			SQL:         "SELECT id, address.city FROM users",
+			expect:      []string{"u1 LA", "u2 SF", "u3 LA"},
+		},
+		{
+			description: "quoted field path",
+			SQL:         "SELECT id, `meta`.`a-b` FROM users",
+			expect:      []string{"u1 x", "u2 y", "u3 z"},
+		},
+		{
+			description: "nested field filter",
+			SQL:         "SELECT id, name FROM users WHERE address.city = ?",
+			args:        []interface{}{"LA"},
+			expectQuery: "address.city == 'LA'",
+			expect:      []string{"u1 a", "u3 c"},
+		},
+		{
+			description: "quoted field path filter",
+			SQL:         "SELECT id, name FROM users WHERE `meta`.`a-b` = ?",
+			args:        []interface{}{"y"},
+			expectQuery: "meta.`a-b` == 'y'",
+			expect:      []string{"u2 b"},
+		},
+		{
+			description: "nested field ordering",
+			SQL:         "SELECT id, name FROM users ORDER BY address.zip DESC",
+			expectQuery: "ORDER BY address.zip DESCENDING",
+			expect:      []string{"u2 b", "u3 c", "u1 a"},
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.putData("users/u1", map[string]interface{}{"name": "a", "address": map[string]interface{}{"city": "LA", "zip": "90001"}, "meta": map[string]interface{}{"a-b": "x"}})
+	fake.putData("users/u2", map[string]interface{}{"name": "b", "address": map[string]interface{}{"city": "SF", "zip": "94105"}, "meta": map[string]interface{}{"a-b": "y"}})
+	fake.putData("users/u3", map[string]interface{}{"name": "c", "address": map[string]interface{}{"city": "LA", "zip": "90002"}, "meta": map[string]interface{}{"a-b": "z"}})
+	for _, testCase := range testCases {
+		rows, err := db.Query(testCase.SQL, testCase.args...)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []string
+		for rows.Next() {
+			var id, value string
+			if err = rows.Scan(&id, &value); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			actual = append(actual, id+" "+value)
+		}
+		rows.Close()
+		query := filterText(fake.lastQuery.GetWhere())
+		for _, order := range fake.lastQuery.GetOrderBy() {
+			query += fmt.Sprintf(" ORDER BY %v %v", order.GetField().GetFieldPath(), order.GetDirection())
+		}
+		if query = strings.TrimSpace(query); query != testCase.expectQuery {
+			t.Errorf("%v: expected query %q, but had %q", testCase.description, testCase.expectQuery, query)
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
+
+func TestStatement_QueryProjection(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		// expectSelect are projected field paths received by the server, nil for whole documents
+		expectSelect []string
+		expect       []string
+	}{
+		{
+			description:  "select list",
+			SQL:          "SELECT name, email FROM users",
+			expectSelect: []string{"name", "email"},
+			expect:       []string{"a a@x", "b b@x"},
+		},
+		{
+			description:  "document ID is always returned",
+			SQL:          "SELECT id, name FROM users",
+			expectSelect: []string{"name"},
+			expect:       []string{"u1 a", "u2 b"},
+		},
+		{
+			description:  "document ID only",
+			SQL:          "SELECT id FROM users",
+			expectSelect: []string{"__name__"},
+			expect:       []string{"u1", "u2"},
+		},
+		{
+			description:  "nested field",
+			SQL:          "SELECT id, address.city FROM users",
+			expectSelect: []string{"address.city"},
+			expect:       []string{"u1 LA", "u2 SF"},
+		},
+		{
+			description:  "nested field of a selected field",
+			SQL:          "SELECT address.city, address FROM users WHERE name = 'a'",
+			expectSelect: []string{"address"},
+			expect:       []string{`LA {"city":"LA","zip":"90001"}`},
+		},
+		{
+			description:  "ORDER BY and client side WHERE columns",
+			SQL:          "SELECT id FROM users WHERE LOWER(email) = 'b@x' ORDER BY name",
+			expectSelect: []string{"name", "email"},
+			expect:       []string{"u2"},
+		},
+		{
+			description: "star",
+			SQL:         "SELECT * FROM users WHERE name = 'a'",
+			expect:      []string{`u1 {"city":"LA","zip":"90001"} a@x a`},
+		},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.putData("users/u1", map[string]interface{}{"name": "a", "email": "a@x", "address": map[string]interface{}{"city": "LA", "zip": "90001"}})
+	fake.putData("users/u2", map[string]interface{}{"name": "b", "email": "b@x", "address": map[string]interface{}{"city": "SF", "zip": "94105"}})
+	for _, testCase := range testCases {
+		fake.lastQuery = nil
+		rows, err := db.Query(testCase.SQL)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		columns, _ := rows.Columns()
+		var actual []string
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			dest := make([]interface{}, len(columns))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			if err = rows.Scan(dest...); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+			var items []string
+			for _, value := range values {
+				if data, ok := value.([]byte); ok { // maps are returned as JSON
+					value = string(data)
+				}
+				items = append(items, fmt.Sprint(value))
+			}
+			actual = append(actual, strings.Join(items, " "))
+		}
+		rows.Close()
+		var selected []string
+		for _, field := range fake.lastQuery.GetSelect().GetFields() {
+			selected = append(selected, field.GetFieldPath())
+		}
+		if !reflect.DeepEqual(selected, testCase.expectSelect) {
+			t.Errorf("%v: expected projection %v, but had %v", testCase.description, testCase.expectSelect, selected)
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
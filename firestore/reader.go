@@ -0,0 +1,89 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// docReader reads documents with the client, or within the Firestore transaction of a connection transaction
+type docReader struct {
+	client      *firestore.Client
+	transaction *firestore.Transaction
+	// readTime is the read time of documents, zero reads the latest documents
+	readTime time.Time
+}
+
+// reader returns document reader of the statement connection
+func (s *Statement) reader() *docReader {
+	result := &docReader{client: s.conn.client}
+	if s.conn.tx != nil {
+		result.transaction = s.conn.tx.transaction
+	}
+	return result
+}
+
+// selectReader returns document reader of SELECT statement reading documents at AS OF TIMESTAMP read time, or readTime setting if zero
+func (s *Statement) selectReader(readTime time.Time) (*docReader, error) {
+	result := s.reader()
+	if result.transaction != nil {
+		// Transaction reads the latest documents
+		if !readTime.IsZero() {
+			return nil, fmt.Errorf("AS OF TIMESTAMP is not supported within transaction")
+		}
+		return result, nil
+	}
+	if readTime.IsZero() {
+		readTime = s.conn.cfg.ReadTime
+	}
+	if readTime.IsZero() {
+		return result, nil
+	}
+	if err := validateReadTime(readTime, time.Now()); err != nil {
+		return nil, err
+	}
+	result.readTime = readTime
+	return result, nil
+}
+
+// context returns the context of read requests, at the read time if set
+func (r *docReader) context(ctx context.Context) context.Context {
+	if r.readTime.IsZero() {
+		return ctx
+	}
+	return withReadTime(ctx, r.readTime)
+}
+
+// documents returns an iterator of query documents
+func (r *docReader) documents(ctx context.Context, queryRef firestore.Query) *firestore.DocumentIterator {
+	if r.transaction != nil {
+		return r.transaction.Documents(queryRef)
+	}
+	return queryRef.Documents(r.context(ctx))
+}
+
+// get returns a document snapshot
+func (r *docReader) get(ctx context.Context, docRef *firestore.DocumentRef) (*firestore.DocumentSnapshot, error) {
+	if r.transaction != nil {
+		return r.transaction.Get(docRef)
+	}
+	return docRef.Get(r.context(ctx))
+}
+
+// getAll returns document snapshots in docRefs order, missing documents are not existing snapshots
+func (r *docReader) getAll(ctx context.Context, docRefs []*firestore.DocumentRef) ([]*firestore.DocumentSnapshot, error) {
+	if r.transaction != nil {
+		return r.transaction.GetAll(docRefs)
+	}
+	return r.client.GetAll(r.context(ctx), docRefs)
+}
+
+// aggregate runs an aggregation query
+func (r *docReader) aggregate(ctx context.Context, aggregationQuery *firestore.AggregationQuery) (firestore.AggregationResult, error) {
+	if r.transaction != nil {
+		aggregationQuery = aggregationQuery.Transaction(r.transaction)
+	}
+	return aggregationQuery.Get(r.context(ctx))
+}
@@ -0,0 +1,139 @@
+package firestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/viant/firebase/shared"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// pitrWindow is the max age of a read time, reads older than one hour require point-in-time recovery (PITR) enabled
+const pitrWindow = 7 * 24 * time.Hour
+
+// asOfExpr matches AS OF TIMESTAMP ? or AS OF TIMESTAMP 'timestamp' clause following the queried table
+var asOfExpr = regexp.MustCompile(`(?i)\s+AS\s+OF\s+TIMESTAMP\s+(\?|'[^']*'|"[^"]*")`)
+
+// asOfReadTime removes AS OF TIMESTAMP clause from SQL returning its read time, zero if not specified;
+// a placeholder is bound from args in SQL order and removed from the returned args
+func asOfReadTime(SQL string, args []driver.NamedValue) (string, time.Time, []driver.NamedValue, error) {
+	match := asOfExpr.FindStringSubmatchIndex(SQL)
+	if match == nil {
+		return SQL, time.Time{}, args, nil
+	}
+	var value interface{} = strings.Trim(SQL[match[2]:match[3]], `'"`)
+	if SQL[match[2]] == '?' {
+		index := checkQueryParameters(SQL[:match[2]])
+		if index >= len(args) {
+			return "", time.Time{}, nil, fmt.Errorf("AS OF TIMESTAMP value not provided")
+		}
+		value = args[index].Value
+		args = append(append([]driver.NamedValue{}, args[:index]...), args[index+1:]...)
+	}
+	readTime, err := shared.AsTime(value)
+	if err != nil {
+		return "", time.Time{}, nil, fmt.Errorf("invalid AS OF TIMESTAMP: %v", err)
+	}
+	return SQL[:match[0]] + SQL[match[1]:], readTime, args, nil
+}
+
+// validateReadTime checks a read time is within the point-in-time recovery window, read times older than one hour
+// have to be whole minutes; Firestore read times have microsecond precision
+func validateReadTime(readTime time.Time, now time.Time) error {
+	if readTime.After(now) {
+		return fmt.Errorf("read time %v is in the future", readTime.Format(time.RFC3339Nano))
+	}
+	if !readTime.Truncate(time.Microsecond).Equal(readTime) {
+		return fmt.Errorf("read time %v has to be a whole microsecond", readTime.Format(time.RFC3339Nano))
+	}
+	age := now.Sub(readTime)
+	if age > pitrWindow {
+		return fmt.Errorf("read time %v is outside of 7 days point-in-time recovery window", readTime.Format(time.RFC3339Nano))
+	}
+	if age > time.Hour && !readTime.Truncate(time.Minute).Equal(readTime) {
+		return fmt.Errorf("read time %v older than one hour has to be a whole minute", readTime.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// readTimeContextKey is the context key of the read time of Firestore read requests
+type readTimeContextKey struct{}
+
+// withReadTime returns the context of read requests at the read time
+func withReadTime(ctx context.Context, readTime time.Time) context.Context {
+	return context.WithValue(ctx, readTimeContextKey{}, timestamppb.New(readTime))
+}
+
+// readTimeInterceptor applies the read time of the context to read requests: firestore v1.15 read options
+// do not apply to collection queries nor to aggregation queries, and are truncated to seconds
+func readTimeInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if readTime, ok := ctx.Value(readTimeContextKey{}).(*timestamppb.Timestamp); ok {
+		return &readTimeStream{ClientStream: stream, readTime: readTime}, nil
+	}
+	return stream, nil
+}
+
+// readTimeStream sets the read time of read requests without a transaction
+type readTimeStream struct {
+	grpc.ClientStream
+	readTime *timestamppb.Timestamp
+}
+
+// SendMsg sends a request
+func (s *readTimeStream) SendMsg(m interface{}) error {
+	switch req := m.(type) {
+	case *pb.RunQueryRequest:
+		if req.ConsistencySelector == nil {
+			req.ConsistencySelector = &pb.RunQueryRequest_ReadTime{ReadTime: s.readTime}
+		}
+	case *pb.RunAggregationQueryRequest:
+		if req.ConsistencySelector == nil {
+			req.ConsistencySelector = &pb.RunAggregationQueryRequest_ReadTime{ReadTime: s.readTime}
+		}
+	case *pb.BatchGetDocumentsRequest:
+		if req.ConsistencySelector == nil {
+			req.ConsistencySelector = &pb.BatchGetDocumentsRequest_ReadTime{ReadTime: s.readTime}
+		}
+	}
+	return s.ClientStream.SendMsg(m)
+}
+
+// readTimeOptions returns client options intercepting read requests, firestore.NewClient dials the emulator
+// of FIRESTORE_EMULATOR_HOST ignoring dial options, the connection is dialed the same way with the interceptor
+func readTimeOptions() ([]option.ClientOption, error) {
+	interceptor := grpc.WithChainStreamInterceptor(readTimeInterceptor)
+	if addr := os.Getenv("FIRESTORE_EMULATOR_HOST"); addr != "" {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithPerRPCCredentials(emulatorCredentials{}), interceptor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial emulator %v: %v", addr, err)
+		}
+		return []option.ClientOption{option.WithGRPCConn(conn)}, nil
+	}
+	return []option.ClientOption{option.WithGRPCDialOption(interceptor)}, nil
+}
+
+// emulatorCredentials authorize emulator requests as firestore.NewClient does
+type emulatorCredentials struct{}
+
+// GetRequestMetadata returns the emulator owner authorization
+func (emulatorCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer owner"}, nil
+}
+
+// RequireTransportSecurity returns false, the emulator is not secured
+func (emulatorCredentials) RequireTransportSecurity() bool {
+	return false
+}
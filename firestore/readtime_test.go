@@ -0,0 +1,184 @@
+package firestore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAsOfReadTime(t *testing.T) {
+	at := time.Date(2024, 3, 5, 10, 7, 0, 0, time.UTC)
+	var testCases = []struct {
+		description    string
+		SQL            string
+		args           []interface{}
+		expectSQL      string
+		expectReadTime time.Time
+		expectArgs     int
+		expectError    bool
+	}{
+		{
+			description: "no clause",
+			SQL:         "SELECT name FROM users WHERE id = ?",
+			args:        []interface{}{"u1"},
+			expectSQL:   "SELECT name FROM users WHERE id = ?",
+			expectArgs:  1,
+		},
+		{
+			description:    "placeholder is removed from args",
+			SQL:            "SELECT name FROM users AS OF TIMESTAMP ? WHERE id = ?",
+			args:           []interface{}{at, "u1"},
+			expectSQL:      "SELECT name FROM users WHERE id = ?",
+			expectReadTime: at,
+			expectArgs:     1,
+		},
+		{
+			description:    "literal",
+			SQL:            "SELECT name FROM users as of timestamp '2024-03-05T10:07:00Z' WHERE id = ?",
+			args:           []interface{}{"u1"},
+			expectSQL:      "SELECT name FROM users WHERE id = ?",
+			expectReadTime: at,
+			expectArgs:     1,
+		},
+		{
+			description: "missing placeholder value",
+			SQL:         "SELECT name FROM users WHERE id = ? AS OF TIMESTAMP ?",
+			args:        []interface{}{"u1"},
+			expectError: true,
+		},
+		{
+			description: "invalid value",
+			SQL:         "SELECT name FROM users AS OF TIMESTAMP 'yesterday'",
+			expectError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		SQL, readTime, args, err := asOfReadTime(testCase.SQL, namedValues(testCase.args...))
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if SQL != testCase.expectSQL || !readTime.Equal(testCase.expectReadTime) || len(args) != testCase.expectArgs {
+			t.Errorf("%v: expected %q %v %v args, but had %q %v %v args", testCase.description, testCase.expectSQL, testCase.expectReadTime, testCase.expectArgs, SQL, readTime, len(args))
+		}
+	}
+}
+
+func TestValidateReadTime(t *testing.T) {
+	now := time.Date(2024, 3, 5, 10, 7, 30, 0, time.UTC)
+	var testCases = []struct {
+		description string
+		readTime    time.Time
+		expectError bool
+	}{
+		{description: "recent", readTime: now.Add(-time.Minute - time.Second)},
+		{description: "older than one hour, whole minute", readTime: now.Add(-2 * time.Hour).Truncate(time.Minute)},
+		{description: "older than one hour, not a whole minute", readTime: now.Add(-2 * time.Hour), expectError: true},
+		{description: "oldest within the window", readTime: now.Add(-pitrWindow).Truncate(time.Minute).Add(time.Minute)},
+		{description: "outside of the window", readTime: now.Add(-8 * 24 * time.Hour).Truncate(time.Minute), expectError: true},
+		{description: "future", readTime: now.Add(time.Second), expectError: true},
+		{description: "recent, microseconds", readTime: now.Add(-time.Minute - 1500*time.Microsecond)},
+		{description: "recent, nanoseconds", readTime: now.Add(-time.Minute - time.Nanosecond), expectError: true},
+	}
+	for _, testCase := range testCases {
+		err := validateReadTime(testCase.readTime, now)
+		if (err != nil) != testCase.expectError {
+			t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+		}
+	}
+}
+
+func TestStatement_QueryAsOf(t *testing.T) {
+	at := time.Now().Add(-2 * time.Hour).Truncate(time.Minute)
+	recent := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	var testCases = []struct {
+		description string
+		DSN         string
+		SQL         string
+		args        []interface{}
+		readTime    time.Time
+		expectRows  int
+		// expectAggregations is the number of aggregation queries run by the server
+		expectAggregations int
+	}{
+		{
+			description: "document by ID",
+			SQL:         "SELECT name FROM users AS OF TIMESTAMP ? WHERE id = ?",
+			args:        []interface{}{at, "u1"},
+			expectRows:  1,
+		},
+		{
+			description: "documents by IDs",
+			SQL:         "SELECT name FROM users AS OF TIMESTAMP ? WHERE id IN (?, ?)",
+			args:        []interface{}{at, "u1", "u2"},
+			expectRows:  2,
+		},
+		{
+			description: "query",
+			SQL:         "SELECT name FROM users AS OF TIMESTAMP ? WHERE name = ?",
+			args:        []interface{}{at, "b"},
+			expectRows:  1,
+		},
+		{
+			description: "read time of DSN",
+			DSN:         "?readTime=" + at.Format(time.RFC3339),
+			SQL:         "SELECT name FROM users WHERE name = ?",
+			args:        []interface{}{"a"},
+			expectRows:  1,
+		},
+		{
+			description:        "aggregation",
+			SQL:                "SELECT COUNT(*) FROM users AS OF TIMESTAMP ? WHERE name = ?",
+			args:               []interface{}{at, "a"},
+			expectRows:         1,
+			expectAggregations: 1,
+		},
+		{
+			description:        "aggregation at read time of DSN",
+			DSN:                "?readTime=" + at.Format(time.RFC3339),
+			SQL:                "SELECT COUNT(*) FROM users",
+			expectRows:         1,
+			expectAggregations: 1,
+		},
+		{
+			description: "sub-second read time",
+			SQL:         "SELECT name FROM users AS OF TIMESTAMP ? WHERE id = ?",
+			args:        []interface{}{recent, "u1"},
+			readTime:    recent,
+			expectRows:  1,
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, testCase.DSN)
+		fake.put("users/u1", map[string]string{"name": "a"})
+		fake.put("users/u2", map[string]string{"name": "b"})
+		rows, err := db.Query(testCase.SQL, testCase.args...)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		count := 0
+		for rows.Next() {
+			count++
+		}
+		rows.Close()
+		if count != testCase.expectRows {
+			t.Errorf("%v: expected %v rows, but had %v", testCase.description, testCase.expectRows, count)
+		}
+		expectReadTime := at
+		if !testCase.readTime.IsZero() {
+			expectReadTime = testCase.readTime
+		}
+		if readTime := fake.readTime; readTime == nil || !readTime.AsTime().Equal(expectReadTime) {
+			t.Errorf("%v: expected read time %v, but had %v", testCase.description, expectReadTime, readTime)
+		}
+		if fake.aggregations != testCase.expectAggregations {
+			t.Errorf("%v: expected %v aggregation queries, but had %v", testCase.description, testCase.expectAggregations, fake.aggregations)
+		}
+	}
+}
@@ -3,19 +3,24 @@ package firestore
 import (
 	"database/sql/driver"
 	"fmt"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
 	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
 	"github.com/viant/sqlparser/query"
 	"io"
 	"reflect"
+	"strings"
 )
 
 // Rows represents a result set for a SQL query
 type Rows struct {
-	dryRun      bool
-	columns     []string
-	columnTypes []string
-	values      [][]interface{}
-	currentRow  int
+	dryRun     bool
+	columns    []string
+	values     [][]interface{}
+	currentRow int
+	// stream reads rows from a live document iterator, nil for buffered rows
+	stream *rowStream
 }
 
 // Columns returns the names of the columns
@@ -25,45 +30,76 @@ func (r *Rows) Columns() []string {
 
 // Close closes the rows iterator
 func (r *Rows) Close() error {
+	if r.stream != nil {
+		r.stream.close()
+	}
 	return nil
 }
 
 // Next moves the cursor to the next row
 func (r *Rows) Next(dest []driver.Value) error {
+	if r.stream != nil {
+		row, err := r.stream.next()
+		if err != nil {
+			return err
+		}
+		return setDest(dest, row)
+	}
 	if r.currentRow >= len(r.values) || r.dryRun {
 		return io.EOF
 	}
 
 	// Copy the current row's values to dest
-	for i, val := range r.values[r.currentRow] {
-		dest[i] = val
-	}
+	row := r.values[r.currentRow]
 	r.currentRow++
+	return setDest(dest, row)
+}
+
+// setDest converts row values into database/sql values
+func setDest(dest []driver.Value, row []interface{}) error {
+	for i, val := range row {
+		value, err := driverValue(val)
+		if err != nil {
+			return fmt.Errorf("failed to convert value of column %d: %v", i, err)
+		}
+		dest[i] = value
+	}
 	return nil
 }
 
-// ColumnTypeScanType returns the ScanType of the column at the given index
+// ColumnTypeScanType returns the ScanType of the column at the given index,
+// Map, Array, GeoPoint and Reference for map, array, geographical point and document reference values
 func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
-	if len(r.values) == 0 || index >= len(r.values[0]) {
+	first := r.firstRow()
+	if index >= len(first) || first[index] == nil {
 		return nil
 	}
-	return reflect.TypeOf(r.values[0][index])
+	if scanType, ok := scanTypes[databaseTypeName(first[index])]; ok {
+		return scanType
+	}
+	return reflect.TypeOf(first[index])
+}
+
+// firstRow returns values of the first row, nil for empty result set
+func (r *Rows) firstRow() []interface{} {
+	if r.stream != nil {
+		return r.stream.sample
+	}
+	if len(r.values) == 0 {
+		return nil
+	}
+	return r.values[0]
 }
 
-// ColumnTypeDatabaseTypeName returns the database type name of the column
+// ColumnTypeDatabaseTypeName returns the database type name of the column:
+// STRING, INT64, FLOAT64, BOOL, TIMESTAMP, BYTES, MAP, ARRAY, GEOPOINT or REFERENCE
 func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
-	rType := r.ColumnTypeScanType(index)
-	if rType != nil {
-		ret := rType.Name()
-		if ret != "" {
-			return ret
-		}
-		if rType == reflect.TypeOf([]byte{}) {
-			return "BLOB"
-		}
+	first := r.firstRow()
+	if index >= len(first) {
+		// Firestore is schemaless, columns of empty result are reported as STRING
+		return TypeString
 	}
-	// Return a generic type as Firebase is schemaless
-	return "TEXT"
+	return databaseTypeName(first[index])
 }
 
 // ColumnTypeNullable reports whether the column may be null
@@ -78,7 +114,7 @@ func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
 }
 
 // NewRows creates a new result set from map values
-func NewRows(results []map[string]interface{}, dryRun bool, selectStmt *query.Select) *Rows {
+func NewRows(results []map[string]interface{}, dryRun bool, selectStmt *query.Select) (*Rows, error) {
 	rows := &Rows{
 		dryRun:     dryRun,
 		currentRow: 0,
@@ -87,60 +123,107 @@ func NewRows(results []map[string]interface{}, dryRun bool, selectStmt *query.Se
 	if len(results) == 0 {
 		rows.columns = []string{}
 		rows.values = [][]interface{}{}
-		return rows
+		return rows, nil
 	}
 
 	// Determine columns from SELECT statement
-	rows.columns = extractColumns(selectStmt, results[0])
-	rows.columnTypes = make([]string, len(rows.columns))
+	var columns []*resultColumn
+	rows.columns, columns = extractColumns(selectStmt, results)
 
 	// Transform map values to row values
 	rows.values = make([][]interface{}, len(results))
 	for i, result := range results {
-		row := make([]interface{}, len(rows.columns))
-		for j, col := range rows.columns {
-			row[j] = result[col]
-
-			// For the first row, determine column types
-			if i == 0 && result[col] != nil {
-				rows.columnTypes[j] = reflect.TypeOf(result[col]).String()
-			}
+		row, err := rowValues(result, columns)
+		if err != nil {
+			return nil, err
 		}
 		rows.values[i] = row
 	}
 
-	return rows
+	return rows, nil
+}
+
+// resultColumn represents a source of column values: a field path or a select list expression evaluated per result
+type resultColumn struct {
+	path []string
+	expr node.Node
 }
 
-// extractColumns determines the columns to include in the result set
-func extractColumns(selectStmt *query.Select, firstRow map[string]interface{}) []string {
+// value returns column value of a result
+func (c *resultColumn) value(result map[string]interface{}) (interface{}, error) {
+	if c.expr == nil {
+		return shared.LookupPath(result, c.path), nil
+	}
+	return shared.Evaluate(c.expr, result)
+}
+
+// rowValues returns column values of a result
+func rowValues(result map[string]interface{}, columns []*resultColumn) ([]interface{}, error) {
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value, err := column.value(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate column %d: %v", i+1, err)
+		}
+		row[i] = value
+	}
+	return row, nil
+}
+
+// extractColumns determines the columns to include in the result set and their value sources,
+// nested fields (i.e. address.city) are resolved by walking document maps, other expressions
+// (arithmetic, functions, CASE, CAST) are evaluated per result. Columns are named by alias, field path or SQL text.
+// SELECT * columns are the union of result fields: document ID first, then other fields in alphabetical order
+func extractColumns(selectStmt *query.Select, results []map[string]interface{}) ([]string, []*resultColumn) {
 	// Check if SELECT * is used
 	if selectStmt.List.IsStarExpr() {
 		// Include all columns in the result
-		columns := make([]string, 0, len(firstRow))
-		for col := range firstRow {
-			columns = append(columns, col)
+		fields := shared.StarColumns(results, DocIDColumn)
+		names := make([]string, 0, len(fields))
+		columns := make([]*resultColumn, 0, len(fields))
+		for _, col := range fields {
+			if col == parentKey || col == cursorKey {
+				continue
+			}
+			names = append(names, col)
+			columns = append(columns, &resultColumn{path: []string{col}})
 		}
-		return columns
+		return names, columns
 	}
 
 	// Use specified columns from SELECT clause
-	columns := make([]string, len(selectStmt.List))
+	names := make([]string, len(selectStmt.List))
+	columns := make([]*resultColumn, len(selectStmt.List))
+	aggregated := shared.IsAggregate(selectStmt)
 	for i, item := range selectStmt.List {
+		names[i] = shared.ItemName(item)
+		if aggregated {
+			// aggregated results are keyed by alias, field path or SQL text
+			columns[i] = &resultColumn{path: []string{names[i]}}
+			continue
+		}
 		switch anExpr := item.Expr.(type) {
-		case *expr.Ident:
-			columns[i] = anExpr.Name
-		case *expr.Selector:
-			columns[i] = anExpr.Name
-			if anExpr.X != nil {
-				if ident, ok := anExpr.X.(*expr.Ident); ok {
-					columns[i] = fmt.Sprintf("%s.%s", ident.Name, anExpr.Name)
+		case *expr.Ident, *expr.Selector:
+			columns[i] = &resultColumn{path: shared.FieldPath(sqlparser.Stringify(anExpr))}
+			continue
+		case *expr.Call:
+			if isParentColumn(anExpr) {
+				if item.Alias == "" {
+					names[i] = strings.ToLower(parentFunction)
+				}
+				columns[i] = &resultColumn{path: []string{parentKey}}
+				continue
+			}
+			if isCursorColumn(anExpr) {
+				if item.Alias == "" {
+					names[i] = strings.ToLower(cursorFunction)
 				}
+				columns[i] = &resultColumn{path: []string{cursorKey}}
+				continue
 			}
-		default:
-			columns[i] = fmt.Sprintf("col%d", i)
 		}
+		columns[i] = &resultColumn{expr: shared.Normalize(item.Expr)}
 	}
 
-	return columns
+	return names, columns
 }
@@ -41,6 +41,13 @@ func (s *Statement) Exec(args []driver.Value) (driver.Result, error) {
 
 // ExecContext executes a non-query statement with context
 func (s *Statement) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if t := s.conn.tx; t != nil {
+		return t.exec(ctx, s, args)
+	}
+	return s.exec(ctx, args)
+}
+
+func (s *Statement) exec(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	switch s.kind {
 	case sqlparser.KindInsert:
 		return s.execInsert(ctx, args)
@@ -66,6 +73,13 @@ func (s *Statement) Query(args []driver.Value) (driver.Rows, error) {
 
 // QueryContext executes a query statement with context
 func (s *Statement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if t := s.conn.tx; t != nil {
+		return t.query(ctx, s, args)
+	}
+	return s.query(ctx, args)
+}
+
+func (s *Statement) query(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	switch s.kind {
 	case sqlparser.KindSelect:
 		return s.querySelect(ctx, args)
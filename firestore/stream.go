@@ -0,0 +1,104 @@
+package firestore
+
+import (
+	"io"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/sqlparser/query"
+	"google.golang.org/api/iterator"
+)
+
+// starSampleSize is the number of documents read ahead to determine SELECT * columns of streamed rows
+const starSampleSize = 100
+
+// rowStream decodes documents of a live query iterator into rows on demand
+type rowStream struct {
+	docs    *planIterator
+	decode  func(doc *firestore.DocumentSnapshot) (map[string]interface{}, error)
+	columns []*resultColumn
+	// offset and limit are applied to documents filtered client side, 0 means none
+	offset int
+	limit  int
+	count  int
+	// buffered are results read ahead to determine columns
+	buffered []map[string]interface{}
+	// sample is the first row
+	sample []interface{}
+}
+
+// newStreamRows creates a result set reading documents on Next, the first document
+// (or a sample of documents for SELECT *) is read ahead to determine columns
+func newStreamRows(docs *planIterator, selectStmt *query.Select, decode func(doc *firestore.DocumentSnapshot) (map[string]interface{}, error), offset, limit int) (*Rows, error) {
+	stream := &rowStream{docs: docs, decode: decode, offset: offset, limit: limit}
+	sampleSize := 1
+	if selectStmt.List.IsStarExpr() {
+		sampleSize = starSampleSize
+	}
+	for len(stream.buffered) < sampleSize {
+		result, err := stream.nextResult()
+		if err == iterator.Done {
+			docs.stop()
+			break
+		}
+		if err != nil {
+			docs.stop()
+			return nil, err
+		}
+		stream.buffered = append(stream.buffered, result)
+	}
+	rows := &Rows{stream: stream, columns: []string{}}
+	if len(stream.buffered) == 0 {
+		return rows, nil
+	}
+	rows.columns, stream.columns = extractColumns(selectStmt, stream.buffered)
+	sample, err := rowValues(stream.buffered[0], stream.columns)
+	if err != nil {
+		docs.stop()
+		return nil, err
+	}
+	stream.sample = sample
+	return rows, nil
+}
+
+// next returns the next row or io.EOF
+func (s *rowStream) next() ([]interface{}, error) {
+	if len(s.buffered) > 0 {
+		result := s.buffered[0]
+		s.buffered = s.buffered[1:]
+		return rowValues(result, s.columns)
+	}
+	if s.sample == nil {
+		return nil, io.EOF
+	}
+	result, err := s.nextResult()
+	if err == iterator.Done {
+		s.docs.stop()
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rowValues(result, s.columns)
+}
+
+// nextResult returns the next decoded document within the window or iterator.Done
+func (s *rowStream) nextResult() (map[string]interface{}, error) {
+	if s.limit > 0 && s.count >= s.limit {
+		return nil, iterator.Done
+	}
+	for ; s.offset > 0; s.offset-- {
+		if _, err := s.docs.next(); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := s.docs.next()
+	if err != nil {
+		return nil, err
+	}
+	s.count++
+	return s.decode(doc)
+}
+
+func (s *rowStream) close() {
+	s.docs.stop()
+}
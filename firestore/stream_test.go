@@ -0,0 +1,155 @@
+package firestore
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/sqlparser"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewStreamRows(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		// nexts is the number of rows read
+		nexts int
+		close bool
+		// expectDecoded is the number of documents decoded once rows are read
+		expectDecoded int
+		expectEOF     bool
+	}{
+		{description: "first document is read ahead", SQL: "SELECT name FROM users", expectDecoded: 1},
+		{description: "documents are decoded on next", SQL: "SELECT name FROM users", nexts: 2, expectDecoded: 2},
+		{description: "documents of SELECT * sample are read ahead", SQL: "SELECT * FROM users", expectDecoded: 3},
+		{description: "last row", SQL: "SELECT name FROM users", nexts: 3, expectDecoded: 3},
+		{description: "close stops the iterator", SQL: "SELECT name FROM users", nexts: 1, close: true, expectDecoded: 1, expectEOF: true},
+	}
+	fake, db := newFakeDB(t, "")
+	fake.put("users/u1", map[string]string{"name": "a"})
+	fake.put("users/u2", map[string]string{"name": "b"})
+	fake.put("users/u3", map[string]string{"name": "c"})
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var client *firestore.Client
+	if err = conn.Raw(func(driverConn interface{}) error {
+		client = driverConn.(*connection).client
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, testCase := range testCases {
+		selectStmt, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan := &queryPlan{queries: []firestore.Query{client.Collection("users").Query}}
+		docs := plan.documents(ctx)
+		decoded := 0
+		rows, err := newStreamRows(docs, selectStmt, func(doc *firestore.DocumentSnapshot) (map[string]interface{}, error) {
+			decoded++
+			return documentData(doc), nil
+		}, 0, 0)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		dest := make([]driver.Value, len(rows.Columns()))
+		for i := 0; i < testCase.nexts; i++ {
+			if err = rows.Next(dest); err != nil {
+				t.Errorf("%v: %v", testCase.description, err)
+			}
+		}
+		if testCase.close {
+			rows.Close()
+			if docs.docIter != nil {
+				t.Errorf("%v: expected stopped iterator", testCase.description)
+			}
+		}
+		if decoded != testCase.expectDecoded {
+			t.Errorf("%v: expected %v decoded documents, but had %v", testCase.description, testCase.expectDecoded, decoded)
+		}
+		if testCase.expectEOF {
+			if err = rows.Next(dest); err != io.EOF {
+				t.Errorf("%v: expected %v, but had %v", testCase.description, io.EOF, err)
+			}
+		}
+		rows.Close()
+	}
+}
+
+func TestStatement_QueryStreamError(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		queryErr    error
+		// cancelAfter cancels the query context after a number of rows, 0 means never
+		cancelAfter int
+		expectRows  int
+		expectCode  codes.Code
+	}{
+		{
+			description: "complete stream",
+			SQL:         "SELECT name FROM users",
+			expectRows:  2,
+		},
+		{
+			description: "iterator error after documents",
+			SQL:         "SELECT name FROM users",
+			queryErr:    status.Error(codes.PermissionDenied, "denied"),
+			expectRows:  2,
+			expectCode:  codes.PermissionDenied,
+		},
+		{
+			description: "iterator error of SELECT * sample",
+			SQL:         "SELECT * FROM users",
+			queryErr:    status.Error(codes.PermissionDenied, "denied"),
+			expectCode:  codes.PermissionDenied,
+		},
+		{
+			description: "context cancellation",
+			SQL:         "SELECT name FROM users",
+			cancelAfter: 1,
+			expectRows:  1,
+			expectCode:  codes.Canceled,
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		fake.put("users/u1", map[string]string{"name": "a"})
+		fake.put("users/u2", map[string]string{"name": "b"})
+		fake.queryErr = testCase.queryErr
+		ctx, cancel := context.WithCancel(context.Background())
+		rows, err := db.QueryContext(ctx, testCase.SQL)
+		if err == nil {
+			count := 0
+			for rows.Next() {
+				if count++; count == testCase.cancelAfter {
+					cancel()
+				}
+			}
+			err = rows.Err()
+			rows.Close()
+			if count != testCase.expectRows {
+				t.Errorf("%v: expected %v rows, but had %v", testCase.description, testCase.expectRows, count)
+			}
+		}
+		cancel()
+		code := status.Code(err)
+		if errors.Is(err, context.Canceled) {
+			code = codes.Canceled
+		}
+		if code != testCase.expectCode {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expectCode, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package firestore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+const (
+	arrayUnionFunction   = "ARRAY_UNION"
+	arrayRemoveFunction  = "ARRAY_REMOVE"
+	currentTimestampName = "CURRENT_TIMESTAMP"
+	nowFunction          = "NOW"
+)
+
+// evaluateFieldValue evaluates INSERT or UPDATE SET value of a column, atomic field transforms are translated into Firestore sentinels:
+// column + ? and column - ? into firestore.Increment, ARRAY_UNION(column, ?) and ARRAY_REMOVE(column, ?) into firestore.ArrayUnion
+// and firestore.ArrayRemove (slice arguments are expanded into elements), CURRENT_TIMESTAMP and NOW() into firestore.ServerTimestamp,
+// DELETE_FIELD() into firestore.Delete
+func (e *evaluator) evaluateFieldValue(column string, n node.Node, argIndex *int) (interface{}, error) {
+	switch actual := n.(type) {
+	case *expr.Ident:
+		if strings.EqualFold(actual.Name, currentTimestampName) {
+			return firestore.ServerTimestamp, nil
+		}
+	case *expr.Binary:
+		if (actual.Op == "+" || actual.Op == "-") && isColumnOf(actual.X, column) {
+			delta, err := e.evaluateExprWithArgIndex(actual.Y, argIndex)
+			if err != nil {
+				return nil, err
+			}
+			return increment(delta, actual.Op == "-")
+		}
+	case *expr.Call:
+		name := strings.ToUpper(sqlparser.Stringify(actual.X))
+		switch name {
+		case currentTimestampName, nowFunction:
+			if len(actual.Args) != 0 {
+				return nil, fmt.Errorf("%s expects no arguments", name)
+			}
+			return firestore.ServerTimestamp, nil
+		case deleteFieldFunction:
+			if len(actual.Args) != 0 {
+				return nil, fmt.Errorf("%s expects no arguments", name)
+			}
+			return firestore.Delete, nil
+		case arrayUnionFunction, arrayRemoveFunction:
+			args := actual.Args
+			if len(args) > 0 && isColumnOf(args[0], column) {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%s expects elements", name)
+			}
+			var elements []interface{}
+			for _, arg := range args {
+				value, err := e.evaluateExprWithArgIndex(arg, argIndex)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, shared.ExpandSlice(value)...)
+			}
+			if name == arrayUnionFunction {
+				return firestore.ArrayUnion(elements...), nil
+			}
+			return firestore.ArrayRemove(elements...), nil
+		}
+	}
+	return e.evaluateExprWithArgIndex(n, argIndex)
+}
+
+// isColumnOf returns true if the expression references the column
+func isColumnOf(n node.Node, column string) bool {
+	switch n.(type) {
+	case *expr.Ident, *expr.Selector:
+		return strings.Join(shared.FieldPath(sqlparser.Stringify(n)), pathSeparator) == strings.Join(shared.FieldPath(column), pathSeparator)
+	}
+	return false
+}
+
+// increment returns firestore.Increment of a numeric delta, negated for subtraction
+func increment(delta interface{}, negative bool) (interface{}, error) {
+	value := reflect.ValueOf(delta)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if negative {
+			return firestore.Increment(-value.Int()), nil
+		}
+		return firestore.Increment(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if negative {
+			return firestore.Increment(-int64(value.Uint())), nil
+		}
+		return firestore.Increment(int64(value.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		if negative {
+			return firestore.Increment(-value.Float()), nil
+		}
+		return firestore.Increment(value.Float()), nil
+	}
+	return nil, fmt.Errorf("increment expects numeric value, but had: %T", delta)
+}
@@ -0,0 +1,109 @@
+package firestore
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+func TestStatement_ExecTransforms(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		// expectMasks are update masks of writes received by the server
+		expectMasks []string
+		// expectTransforms are field transforms of writes received by the server
+		expectTransforms []string
+	}{
+		{
+			description:      "increment",
+			SQL:              "UPDATE posts SET views = views + 1 WHERE id = ?",
+			args:             []interface{}{"p1"},
+			expectTransforms: []string{"views increment 1"},
+		},
+		{
+			description:      "decrement by placeholder",
+			SQL:              "UPDATE posts SET views = views - ? WHERE id = ?",
+			args:             []interface{}{2, "p1"},
+			expectTransforms: []string{"views increment -2"},
+		},
+		{
+			description:      "array union",
+			SQL:              "UPDATE posts SET tags = ARRAY_UNION(tags, ?) WHERE id = ?",
+			args:             []interface{}{[]string{"go", "sql"}, "p1"},
+			expectTransforms: []string{"tags array union ['go', 'sql']"},
+		},
+		{
+			description:      "array remove",
+			SQL:              "UPDATE posts SET tags = ARRAY_REMOVE(tags, ?) WHERE id = ?",
+			args:             []interface{}{[]string{"go"}, "p1"},
+			expectTransforms: []string{"tags array remove ['go']"},
+		},
+		{
+			description:      "current timestamp",
+			SQL:              "UPDATE posts SET updated = CURRENT_TIMESTAMP WHERE id = ?",
+			args:             []interface{}{"p1"},
+			expectTransforms: []string{"updated server timestamp"},
+		},
+		{
+			description:      "transforms with a value",
+			SQL:              "UPDATE posts SET title = ?, views = views + 1, updated = NOW() WHERE title = ?",
+			args:             []interface{}{"b", "a"},
+			expectMasks:      []string{"title"},
+			expectTransforms: []string{"updated server timestamp", "views increment 1"},
+		},
+		{
+			description:      "insert",
+			SQL:              "INSERT INTO posts (id, title, created) VALUES (?, ?, NOW())",
+			args:             []interface{}{"p2", "b"},
+			expectTransforms: []string{"created server timestamp"},
+		},
+		{
+			description:      "bulk insert",
+			SQL:              "INSERT INTO posts (id, created) VALUES (?, CURRENT_TIMESTAMP), (?, NOW())",
+			args:             []interface{}{"p2", "p3"},
+			expectTransforms: []string{"created server timestamp", "created server timestamp"},
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		fake.putData("posts/p1", map[string]interface{}{"title": "a", "views": 1, "tags": []interface{}{"go"}})
+		if _, err := db.Exec(testCase.SQL, testCase.args...); err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var masks, transforms []string
+		for _, write := range fake.writes {
+			masks = append(masks, write.GetUpdateMask().GetFieldPaths()...)
+			for _, transform := range write.GetUpdateTransforms() {
+				transforms = append(transforms, transformText(transform))
+			}
+		}
+		sort.Strings(transforms)
+		if !reflect.DeepEqual(masks, testCase.expectMasks) {
+			t.Errorf("%v: expected masks %v, but had %v", testCase.description, testCase.expectMasks, masks)
+		}
+		if !reflect.DeepEqual(transforms, testCase.expectTransforms) {
+			t.Errorf("%v: expected transforms %v, but had %v", testCase.description, testCase.expectTransforms, transforms)
+		}
+	}
+}
+
+// transformText returns a field transform as text
+func transformText(transform *pb.DocumentTransform_FieldTransform) string {
+	result := transform.GetFieldPath()
+	switch actual := transform.GetTransformType().(type) {
+	case *pb.DocumentTransform_FieldTransform_Increment:
+		return result + " increment " + valueText(actual.Increment)
+	case *pb.DocumentTransform_FieldTransform_AppendMissingElements:
+		return result + " array union " + valueText(&pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: actual.AppendMissingElements}})
+	case *pb.DocumentTransform_FieldTransform_RemoveAllFromArray:
+		return result + " array remove " + valueText(&pb.Value{ValueType: &pb.Value_ArrayValue{ArrayValue: actual.RemoveAllFromArray}})
+	case *pb.DocumentTransform_FieldTransform_SetToServerValue:
+		return result + " server timestamp"
+	}
+	return result + " " + transform.String()
+}
@@ -2,35 +2,331 @@ package firestore
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/sqlparser"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// errRollback rolls back Firestore transaction
+	errRollback = errors.New("transaction rolled back")
+	// ErrTxConflict is returned by Commit of a transaction retried after contention, statements of which returned different results
+	ErrTxConflict = errors.New("transaction conflict: results of the retried transaction changed")
 )
 
-// tx represents a transaction
+// tx represents a Firestore transaction run by client.RunTransaction: statements read within the transaction,
+// writes are buffered and applied to the transaction on Commit. If Firestore aborts the commit due to contention,
+// the transaction is retried by replaying statements, Commit fails with ErrTxConflict if any replayed statement returns different rows
+// or affects a different number of documents
 type tx struct {
-	conn   *connection
-	closed bool
-	ctx    context.Context
-	opts   driver.TxOptions
+	conn        *connection
+	closed      bool
+	ctx         context.Context
+	opts        driver.TxOptions
+	transaction *firestore.Transaction
+	// writes are buffered writes applied to Firestore transaction on Commit
+	writes []func(transaction *firestore.Transaction) error
+	// statements are executed statements replayed on transaction retry
+	statements []*txStatement
+	// generated are IDs of documents inserted with auto-generated ID by the executed statement
+	generated []string
+	// replayed are auto-generated IDs reused by the replayed statement
+	replayed []string
+	attempts int
+	started  chan struct{}
+	finish   chan error
+	done     chan error
 }
 
-// Commit commits the transaction
-func (t *tx) Commit() error {
+// txStatement represents a statement executed within a transaction
+type txStatement struct {
+	stmt    *Statement
+	args    []driver.NamedValue
+	query   bool
+	columns []string
+	values  [][]interface{}
+	result  *Result
+	// docIDs are auto-generated IDs of inserted documents
+	docIDs []string
+}
+
+// newTx starts Firestore transaction
+func newTx(ctx context.Context, conn *connection, opts driver.TxOptions) (*tx, error) {
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault, sql.LevelSerializable:
+	default:
+		return nil, fmt.Errorf("unsupported transaction isolation level: %v", sql.IsolationLevel(opts.Isolation))
+	}
+	result := &tx{
+		conn:    conn,
+		ctx:     ctx,
+		opts:    opts,
+		started: make(chan struct{}),
+		finish:  make(chan error, 1),
+		done:    make(chan error, 1),
+	}
+	var txOptions []firestore.TransactionOption
+	if opts.ReadOnly {
+		txOptions = append(txOptions, firestore.ReadOnly)
+	}
+	if conn.cfg.TxMaxAttempts > 0 {
+		txOptions = append(txOptions, firestore.MaxAttempts(conn.cfg.TxMaxAttempts))
+	}
+	go func() {
+		result.done <- conn.client.RunTransaction(ctx, result.run, txOptions...)
+	}()
+	select {
+	case <-result.started:
+		return result, nil
+	case err := <-result.done:
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+}
+
+// run runs an attempt of Firestore transaction: the first attempt waits for Commit or Rollback,
+// retries replay executed statements before buffered writes are applied
+func (t *tx) run(ctx context.Context, transaction *firestore.Transaction) error {
+	t.transaction = transaction
+	if t.attempts++; t.attempts == 1 {
+		close(t.started)
+		select {
+		case err := <-t.finish:
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	} else if err := t.replay(ctx); err != nil {
+		return err
+	}
+	for _, write := range t.writes {
+		if err := write(transaction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replay executes statements of the transaction again, a statement failing, returning different rows or affecting
+// a different number of documents fails with ErrTxConflict
+func (t *tx) replay(ctx context.Context) error {
+	statements := t.statements
+	t.statements, t.writes = nil, nil
+	for _, statement := range statements {
+		if !statement.query {
+			t.replayed = statement.docIDs
+			result, err := t.exec(ctx, statement.stmt, statement.args)
+			if err != nil {
+				return fmt.Errorf("%w: %v: %v", ErrTxConflict, statement.stmt.SQL, err)
+			}
+			if actual, ok := result.(*Result); !ok || statement.result == nil || *actual != *statement.result {
+				return fmt.Errorf("%w: %v", ErrTxConflict, statement.stmt.SQL)
+			}
+			continue
+		}
+		rows, err := t.query(ctx, statement.stmt, statement.args)
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrTxConflict, statement.stmt.SQL, err)
+		}
+		if !reflect.DeepEqual(rows.columns, statement.columns) || !reflect.DeepEqual(rows.values, statement.values) {
+			return fmt.Errorf("%w: %v", ErrTxConflict, statement.stmt.SQL)
+		}
+	}
+	return nil
+}
+
+// exec executes a statement within the transaction, writes buffered by a failed statement are discarded
+func (t *tx) exec(ctx context.Context, stmt *Statement, args []driver.NamedValue) (driver.Result, error) {
+	switch stmt.kind {
+	case sqlparser.KindCreateTable, sqlparser.KindDropTable:
+		return nil, fmt.Errorf("unsupported statement within transaction: %s", stmt.SQL)
+	}
+	mark := len(t.writes)
+	t.generated = nil
+	result, err := stmt.exec(ctx, args)
+	if err != nil {
+		t.writes = t.writes[:mark]
+		return nil, err
+	}
+	statement := &txStatement{stmt: stmt, args: args, docIDs: t.generated}
+	if actual, ok := result.(*Result); ok {
+		statement.result = actual
+	}
+	t.statements = append(t.statements, statement)
+	return result, nil
+}
+
+// query executes a query within the transaction, rows are buffered to be compared on replay
+func (t *tx) query(ctx context.Context, stmt *Statement, args []driver.NamedValue) (*Rows, error) {
+	rows, err := stmt.query(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := rows.(*Rows)
+	if !ok || result.stream != nil {
+		return nil, fmt.Errorf("unsupported rows within transaction: %T", rows)
+	}
+	t.statements = append(t.statements, &txStatement{stmt: stmt, args: args, query: true, columns: result.columns, values: result.values})
+	return result, nil
+}
+
+// write buffers a write applied to Firestore transaction on Commit
+func (t *tx) write(write func(transaction *firestore.Transaction) error) error {
+	if t.opts.ReadOnly {
+		return fmt.Errorf("write in read-only transaction")
+	}
+	t.writes = append(t.writes, write)
+	return nil
+}
+
+// insert buffers inserted rows, rows with document ID are checked for existence within the transaction
+// and resolved according to conflict resolution
+func (t *tx) insert(insertStmt *insertStatement, collectionRef *firestore.CollectionRef, docIDs []string, rows []map[string]interface{}, updates [][]firestore.Update) (int64, string, error) {
+	rowsAffected := int64(0)
+	lastInsertID := ""
+	for i, data := range rows {
+		var docRef *firestore.DocumentRef
+		switch {
+		case docIDs[i] == "":
+			docRef = t.newDoc(collectionRef)
+		case insertStmt.conflict == conflictReplace:
+			docRef = collectionRef.Doc(docIDs[i])
+			if err := t.write(func(transaction *firestore.Transaction) error { return transaction.Set(docRef, data) }); err != nil {
+				return 0, "", err
+			}
+			rowsAffected++
+			lastInsertID = docRef.ID
+			continue
+		default:
+			docRef = collectionRef.Doc(docIDs[i])
+			doc, err := t.transaction.Get(docRef)
+			if doc == nil || !doc.Exists() {
+				if status.Code(err) != codes.NotFound {
+					return 0, "", fmt.Errorf("failed to get document %v: %v", documentPath(docRef), err)
+				}
+				break
+			}
+			switch insertStmt.conflict {
+			case conflictIgnore:
+				continue
+			case conflictUpdate:
+				if err = t.update(docRef, updates[i], UpdateModeUpdate); err != nil {
+					return 0, "", err
+				}
+				rowsAffected++
+				lastInsertID = docRef.ID
+				continue
+			}
+			return 0, "", &DuplicateKeyError{Path: documentPath(docRef)}
+		}
+		if err := t.write(func(transaction *firestore.Transaction) error { return transaction.Create(docRef, data) }); err != nil {
+			return 0, "", err
+		}
+		rowsAffected++
+		lastInsertID = docRef.ID
+	}
+	return rowsAffected, lastInsertID, nil
+}
+
+// newDoc returns a document reference with auto-generated ID, a replayed statement reuses IDs of its first execution
+func (t *tx) newDoc(collectionRef *firestore.CollectionRef) *firestore.DocumentRef {
+	var docRef *firestore.DocumentRef
+	if len(t.replayed) > 0 {
+		docRef, t.replayed = collectionRef.Doc(t.replayed[0]), t.replayed[1:]
+	} else {
+		docRef = collectionRef.NewDoc()
+	}
+	t.generated = append(t.generated, docRef.ID)
+	return docRef
+}
+
+// update buffers field updates of a document with update mode
+func (t *tx) update(docRef *firestore.DocumentRef, updates []firestore.Update, mode string) error {
+	return t.write(func(transaction *firestore.Transaction) error {
+		switch mode {
+		case UpdateModeUpdate:
+			return transaction.Update(docRef, updates)
+		case UpdateModeMerge:
+			data, paths := updateData(updates)
+			return transaction.Set(docRef, data, firestore.Merge(paths...))
+		}
+		data, _ := updateData(updates)
+		return transaction.Set(docRef, data)
+	})
+}
+
+// delete buffers a document deletion
+func (t *tx) delete(docRef *firestore.DocumentRef) error {
+	return t.write(func(transaction *firestore.Transaction) error { return transaction.Delete(docRef) })
+}
+
+// end completes the transaction with Commit (nil) or Rollback (errRollback)
+func (t *tx) end(finish error) error {
 	if t.closed {
 		return driver.ErrBadConn
 	}
 	t.closed = true
-	// Firebase Realtime Database does not support traditional transactions;
-	// Implement transaction logic if needed using Firebase transactions
+	t.finish <- finish
+	err := <-t.done
+	t.conn.tx = nil
+	return err
+}
+
+// Commit commits the transaction, buffered writes are applied atomically
+func (t *tx) Commit() error {
+	if err := t.end(nil); err != nil {
+		if errors.Is(err, ErrTxConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
-// Rollback rolls back the transaction
+// Rollback rolls back the transaction, buffered writes are discarded
 func (t *tx) Rollback() error {
-	if t.closed {
-		return driver.ErrBadConn
+	if err := t.end(errRollback); err != nil && err != errRollback {
+		return fmt.Errorf("failed to rollback transaction: %w", err)
 	}
-	t.closed = true
-	// Firebase Realtime Database does not support traditional transactions;
-	// Implement rollback logic if needed
 	return nil
 }
+
+// txWriter buffers writes of a multi-document statement within a transaction
+type txWriter struct {
+	tx           *tx
+	rowsAffected int64
+	err          error
+}
+
+// update buffers field updates of a document with update mode
+func (w *txWriter) update(docRef *firestore.DocumentRef, updates []firestore.Update, mode string) {
+	w.add(w.tx.update(docRef, updates, mode))
+}
+
+// delete buffers a document deletion
+func (w *txWriter) delete(docRef *firestore.DocumentRef) {
+	w.add(w.tx.delete(docRef))
+}
+
+func (w *txWriter) add(err error) {
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return
+	}
+	w.rowsAffected++
+}
+
+// end returns the number of buffered writes
+func (w *txWriter) end() (int64, error) {
+	return w.rowsAffected, w.err
+}
@@ -0,0 +1,251 @@
+package firestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+func TestTx_Commit(t *testing.T) {
+	var testCases = []struct {
+		description string
+		options     *sql.TxOptions
+		// aborts is the number of commits aborted due to contention
+		aborts int
+		// onAbort changes documents before an aborted transaction is retried
+		onAbort     func(fake *fakeServer)
+		run         func(tx *sql.Tx, fake *fakeServer) error
+		rollback    bool
+		expectDocs  map[string]map[string]string
+		expectError error
+	}{
+		{
+			description: "buffered writes are applied on commit",
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				if _, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", "u2", "b"); err != nil {
+					return err
+				}
+				if _, err := tx.Exec("UPDATE users SET name = ? WHERE id = ?", "c", "u1"); err != nil {
+					return err
+				}
+				if docs := fake.snapshot(); len(docs) != 1 {
+					return errors.New("writes are not buffered")
+				}
+				return nil
+			},
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "c"}, "users/u2": {"name": "b"}},
+		},
+		{
+			description: "buffered writes are discarded on rollback",
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				_, err := tx.Exec("DELETE FROM users WHERE id = ?", "u1")
+				return err
+			},
+			rollback:   true,
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "a"}},
+		},
+		{
+			description: "duplicate key fails the statement only",
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				if _, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", "u1", "b"); !errors.Is(err, ErrDuplicateKey) {
+					return errors.New("expected duplicate key")
+				}
+				_, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name", "u1", "c")
+				return err
+			},
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "c"}},
+		},
+		{
+			description: "retry with unchanged reads",
+			aborts:      1,
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				var name string
+				if err := tx.QueryRow("SELECT name FROM users WHERE id = ?", "u1").Scan(&name); err != nil {
+					return err
+				}
+				_, err := tx.Exec("UPDATE users SET name = ? WHERE id = ?", name+"b", "u1")
+				return err
+			},
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "ab"}},
+		},
+		{
+			description: "retry with changed read value",
+			aborts:      1,
+			onAbort: func(fake *fakeServer) {
+				fake.docs[fakeDocumentName("users/u1")].Fields["name"].ValueType = &pb.Value_StringValue{StringValue: "z"}
+			},
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				var name string
+				if err := tx.QueryRow("SELECT name FROM users WHERE id = ?", "u1").Scan(&name); err != nil {
+					return err
+				}
+				_, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", "u2", name)
+				return err
+			},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "z"}},
+			expectError: ErrTxConflict,
+		},
+		{
+			description: "retry with deleted read document",
+			aborts:      1,
+			onAbort: func(fake *fakeServer) {
+				delete(fake.docs, fakeDocumentName("users/u1"))
+			},
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				var name string
+				if err := tx.QueryRow("SELECT name FROM users WHERE id = ?", "u1").Scan(&name); err != nil {
+					return err
+				}
+				_, err := tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", "u2", name)
+				return err
+			},
+			expectDocs:  map[string]map[string]string{},
+			expectError: ErrTxConflict,
+		},
+		{
+			description: "retry with changed rows affected",
+			aborts:      1,
+			onAbort: func(fake *fakeServer) {
+				delete(fake.docs, fakeDocumentName("users/u1"))
+			},
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				_, err := tx.Exec("UPDATE users SET name = ? WHERE name = ?", "b", "a")
+				return err
+			},
+			expectDocs:  map[string]map[string]string{},
+			expectError: ErrTxConflict,
+		},
+		{
+			description: "write in read-only transaction",
+			options:     &sql.TxOptions{ReadOnly: true},
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				if _, err := tx.Exec("DELETE FROM users WHERE id = ?", "u1"); err == nil {
+					return errors.New("expected read-only error")
+				}
+				return nil
+			},
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "a"}},
+		},
+		{
+			description: "DDL within transaction",
+			run: func(tx *sql.Tx, fake *fakeServer) error {
+				if _, err := tx.Exec("DROP TABLE users"); err == nil {
+					return errors.New("expected unsupported statement error")
+				}
+				return nil
+			},
+			expectDocs: map[string]map[string]string{"users/u1": {"name": "a"}},
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "?txMaxAttempts=2")
+		fake.put("users/u1", map[string]string{"name": "a"})
+		fake.aborts = testCase.aborts
+		if testCase.onAbort != nil {
+			fake.onAbort = func() { testCase.onAbort(fake) }
+		}
+		tx, err := db.BeginTx(context.Background(), testCase.options)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if err = testCase.run(tx, fake); err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			tx.Rollback()
+			continue
+		}
+		if testCase.rollback {
+			err = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+		if testCase.expectError != nil {
+			if !errors.Is(err, testCase.expectError) {
+				t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+			}
+		} else if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+		}
+		if docs := fake.snapshot(); !reflect.DeepEqual(docs, testCase.expectDocs) {
+			t.Errorf("%v: expected documents %v, but had %v", testCase.description, testCase.expectDocs, docs)
+		}
+	}
+}
+
+func TestTx_ReplayGeneratedIDs(t *testing.T) {
+	fake, db := newFakeDB(t, "")
+	fake.aborts = 1
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec("INSERT INTO users (name) VALUES (?), (?)", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	// A replayed insert generating other IDs would return a different result and fail with ErrTxConflict
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if docs := fake.snapshot(); len(docs) != 2 || fake.begins != 2 {
+		t.Errorf("expected 2 documents of 2 attempts, but had %v of %v", len(docs), fake.begins)
+	}
+}
+
+func TestTx_Begin(t *testing.T) {
+	var testCases = []struct {
+		description string
+		options     *sql.TxOptions
+		expectError bool
+	}{
+		{description: "default isolation", options: &sql.TxOptions{}},
+		{description: "serializable", options: &sql.TxOptions{Isolation: sql.LevelSerializable}},
+		{description: "read-only", options: &sql.TxOptions{ReadOnly: true}},
+		{description: "read committed", options: &sql.TxOptions{Isolation: sql.LevelReadCommitted}, expectError: true},
+	}
+	for _, testCase := range testCases {
+		_, db := newFakeDB(t, "")
+		tx, err := db.BeginTx(context.Background(), testCase.options)
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if err = tx.Rollback(); err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+		}
+	}
+}
+
+func TestConnection_CloseRollsBackTx(t *testing.T) {
+	fake, db := newFakeDB(t, "")
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tx.Exec("INSERT INTO users (id, name) VALUES (?, ?)", "u1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Raw(func(driverConn interface{}) error { return driverConn.(*connection).Close() }); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Commit(); err == nil {
+		t.Errorf("expected commit error of closed connection")
+	}
+	if docs := fake.snapshot(); len(docs) != 0 || fake.rollbacks != 1 {
+		t.Errorf("expected rolled back transaction, but had %v documents, %v rollbacks", len(docs), fake.rollbacks)
+	}
+}
@@ -0,0 +1,255 @@
+package firestore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Database type names of Firestore values
+const (
+	TypeString    = "STRING"
+	TypeInt64     = "INT64"
+	TypeFloat64   = "FLOAT64"
+	TypeBool      = "BOOL"
+	TypeTimestamp = "TIMESTAMP"
+	TypeBytes     = "BYTES"
+	TypeMap       = "MAP"
+	TypeArray     = "ARRAY"
+	TypeGeoPoint  = "GEOPOINT"
+	TypeReference = "REFERENCE"
+)
+
+// Map represents Firestore map value, returned by Rows as JSON bytes
+type Map map[string]interface{}
+
+// Scan scans JSON bytes or a map into Map
+func (m *Map) Scan(src interface{}) error {
+	switch actual := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case map[string]interface{}:
+		*m = actual
+		return nil
+	case Map:
+		*m = actual
+		return nil
+	}
+	return scanJSON(src, (*map[string]interface{})(m), "Map")
+}
+
+// Value returns map as JSON bytes
+func (m Map) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(jsonValue(map[string]interface{}(m)))
+}
+
+// Array represents Firestore array value, returned by Rows as JSON bytes
+type Array []interface{}
+
+// Scan scans JSON bytes or a slice into Array
+func (a *Array) Scan(src interface{}) error {
+	switch actual := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []interface{}:
+		*a = actual
+		return nil
+	case Array:
+		*a = actual
+		return nil
+	}
+	return scanJSON(src, (*[]interface{})(a), "Array")
+}
+
+// Value returns array as JSON bytes
+func (a Array) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(jsonValue([]interface{}(a)))
+}
+
+// GeoPoint represents Firestore geographical point, returned by Rows as JSON bytes
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Scan scans JSON bytes or a Firestore point into GeoPoint
+func (p *GeoPoint) Scan(src interface{}) error {
+	switch actual := src.(type) {
+	case nil:
+		*p = GeoPoint{}
+		return nil
+	case *latlng.LatLng:
+		*p = GeoPoint{Latitude: actual.Latitude, Longitude: actual.Longitude}
+		return nil
+	case GeoPoint:
+		*p = actual
+		return nil
+	}
+	return scanJSON(src, p, "GeoPoint")
+}
+
+// Value returns point as JSON bytes
+func (p GeoPoint) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// LatLng returns Firestore representation of the point
+func (p GeoPoint) LatLng() *latlng.LatLng {
+	return &latlng.LatLng{Latitude: p.Latitude, Longitude: p.Longitude}
+}
+
+// Reference represents Firestore document reference as document path relative to the database, i.e. users/u1
+type Reference string
+
+// Scan scans document path or a Firestore document reference into Reference
+func (r *Reference) Scan(src interface{}) error {
+	switch actual := src.(type) {
+	case nil:
+		*r = ""
+	case string:
+		*r = Reference(actual)
+	case []byte:
+		*r = Reference(actual)
+	case *firestore.DocumentRef:
+		*r = Reference(documentPath(actual))
+	default:
+		return fmt.Errorf("unable to scan %T into Reference", src)
+	}
+	return nil
+}
+
+// Value returns document path
+func (r Reference) Value() (driver.Value, error) {
+	return string(r), nil
+}
+
+func scanJSON(src interface{}, dest interface{}, typeName string) error {
+	var data []byte
+	switch actual := src.(type) {
+	case []byte:
+		data = actual
+	case string:
+		data = []byte(actual)
+	default:
+		return fmt.Errorf("unable to scan %T into %v", src, typeName)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("unable to scan into %v: %v", typeName, err)
+	}
+	return nil
+}
+
+// databaseTypeName returns database type name of a Firestore value
+func databaseTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return TypeString
+	case int64, int:
+		return TypeInt64
+	case float64:
+		return TypeFloat64
+	case bool:
+		return TypeBool
+	case time.Time:
+		return TypeTimestamp
+	case []byte:
+		return TypeBytes
+	case map[string]interface{}:
+		return TypeMap
+	case []interface{}:
+		return TypeArray
+	case *latlng.LatLng:
+		return TypeGeoPoint
+	case *firestore.DocumentRef:
+		return TypeReference
+	}
+	// Firestore is schemaless, NULL or unknown values are reported as STRING
+	return TypeString
+}
+
+// scanTypes represents scan types of database types not supported by database/sql natively
+var scanTypes = map[string]reflect.Type{
+	TypeMap:       reflect.TypeOf(Map{}),
+	TypeArray:     reflect.TypeOf(Array{}),
+	TypeGeoPoint:  reflect.TypeOf(GeoPoint{}),
+	TypeReference: reflect.TypeOf(Reference("")),
+}
+
+// driverValue converts a Firestore value into a database/sql value: maps, arrays and points are returned as JSON bytes,
+// document references as document paths
+func driverValue(value interface{}) (driver.Value, error) {
+	switch actual := value.(type) {
+	case map[string]interface{}:
+		return Map(actual).Value()
+	case []interface{}:
+		return Array(actual).Value()
+	case *latlng.LatLng:
+		return GeoPoint{Latitude: actual.Latitude, Longitude: actual.Longitude}.Value()
+	case *firestore.DocumentRef:
+		return documentPath(actual), nil
+	case int:
+		return int64(actual), nil
+	}
+	return value, nil
+}
+
+// jsonValue converts nested Firestore values into JSON friendly values
+func jsonValue(value interface{}) interface{} {
+	switch actual := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(actual))
+		for k, v := range actual {
+			result[k] = jsonValue(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(actual))
+		for i, v := range actual {
+			result[i] = jsonValue(v)
+		}
+		return result
+	case *latlng.LatLng:
+		return GeoPoint{Latitude: actual.Latitude, Longitude: actual.Longitude}
+	case *firestore.DocumentRef:
+		return documentPath(actual)
+	}
+	return value
+}
+
+// firestoreValue converts a parameter value into a Firestore value: Map, Array, GeoPoint and Reference types
+// are converted into their Firestore counterparts, false is returned for values not handled
+func (c *connection) firestoreValue(value interface{}) (interface{}, bool) {
+	switch actual := value.(type) {
+	case Map:
+		return map[string]interface{}(actual), true
+	case Array:
+		return []interface{}(actual), true
+	case GeoPoint:
+		return actual.LatLng(), true
+	case *GeoPoint:
+		if actual == nil {
+			return nil, true
+		}
+		return actual.LatLng(), true
+	case Reference:
+		docRef := c.client.Doc(string(actual))
+		if docRef == nil {
+			return value, false
+		}
+		return docRef, true
+	}
+	return value, false
+}
@@ -0,0 +1,71 @@
+package firestore
+
+import (
+	"testing"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestGeoPoint_Scan(t *testing.T) {
+	var testCases = []struct {
+		description string
+		src         interface{}
+		expect      GeoPoint
+		expectError bool
+	}{
+		{description: "nil resets the point", src: nil},
+		{description: "lat lng", src: &latlng.LatLng{Latitude: 1.5, Longitude: -2}, expect: GeoPoint{Latitude: 1.5, Longitude: -2}},
+		{description: "JSON", src: []byte(`{"latitude":3,"longitude":4}`), expect: GeoPoint{Latitude: 3, Longitude: 4}},
+		{description: "unsupported", src: 1, expectError: true},
+	}
+	for _, testCase := range testCases {
+		point := GeoPoint{Latitude: 9, Longitude: 9}
+		err := point.Scan(testCase.src)
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if point != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, point)
+		}
+	}
+}
+
+func TestReference_Scan(t *testing.T) {
+	client := newTestClient(t)
+	var testCases = []struct {
+		description string
+		src         interface{}
+		expect      Reference
+		expectError bool
+	}{
+		{description: "nil resets the reference", src: nil},
+		{description: "path", src: "users/u1", expect: "users/u1"},
+		{description: "bytes", src: []byte("users/u1"), expect: "users/u1"},
+		{description: "document reference", src: client.Doc("users/u1/orders/o1"), expect: "users/u1/orders/o1"},
+		{description: "unsupported", src: 1, expectError: true},
+	}
+	for _, testCase := range testCases {
+		ref := Reference("other/o")
+		err := ref.Scan(testCase.src)
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("%v: expected error", testCase.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if ref != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, ref)
+		}
+	}
+}
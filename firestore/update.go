@@ -0,0 +1,109 @@
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser"
+)
+
+// Update modes of UPDATE statement
+const (
+	// UpdateModeUpdate updates fields of an existing document, fails if the document does not exist
+	UpdateModeUpdate = "update"
+	// UpdateModeMerge merges fields into a document (creating it if needed), other fields are kept
+	UpdateModeMerge = "merge"
+	// UpdateModeReplace replaces a whole document with the fields
+	UpdateModeReplace = "replace"
+
+	deleteFieldFunction = "DELETE_FIELD"
+)
+
+var (
+	// unsetExpr matches UNSET column list of UPDATE statement
+	unsetExpr = regexp.MustCompile("(?i)\\bUNSET\\s+([\\w.`]+(?:\\s*,\\s*[\\w.`]+)*)")
+	// setKeywordExpr matches SET keyword
+	setKeywordExpr = regexp.MustCompile(`(?i)\bSET\b`)
+	// updateModeHint matches UPDATE /*+ MERGE */ or UPDATE /*+ REPLACE */ statement hint
+	updateModeHint = regexp.MustCompile(`(?i)^(\s*UPDATE\s+)/\*\+\s*(UPDATE|MERGE|REPLACE)\s*\*/`)
+)
+
+// ReplaceUnset rewrites UPDATE t [SET ...] UNSET x, y into UPDATE t SET ..., x = DELETE_FIELD(), y = DELETE_FIELD()
+func ReplaceUnset(SQL string) string {
+	if sqlparser.ParseKind(SQL) != sqlparser.KindUpdate {
+		return SQL
+	}
+	loc := unsetExpr.FindStringSubmatchIndex(SQL)
+	if loc == nil {
+		return SQL
+	}
+	var assignments []string
+	for _, column := range strings.Split(SQL[loc[2]:loc[3]], ",") {
+		assignments = append(assignments, strings.TrimSpace(column)+" = "+deleteFieldFunction+"()")
+	}
+	prefix := strings.TrimRight(SQL[:loc[0]], " \t\r\n")
+	keyword := " SET "
+	if setKeywordExpr.MatchString(prefix) {
+		keyword = ", "
+	}
+	return prefix + keyword + strings.Join(assignments, ", ") + SQL[loc[1]:]
+}
+
+// updateMode returns update mode of UPDATE statement hint or the default mode, and SQL without the hint
+func updateMode(SQL string, defaultMode string) (string, string) {
+	match := updateModeHint.FindStringSubmatch(SQL)
+	if match == nil {
+		if defaultMode == "" {
+			defaultMode = UpdateModeUpdate
+		}
+		return defaultMode, SQL
+	}
+	return strings.ToLower(match[2]), match[1] + SQL[len(match[0]):]
+}
+
+// writeUpdates writes field updates to a document with update mode: Update for update mode,
+// Set with Merge of updated field paths for merge mode and Set for replace mode
+func writeUpdates(ctx context.Context, docRef *firestore.DocumentRef, updates []firestore.Update, mode string) error {
+	if mode == UpdateModeUpdate {
+		_, err := docRef.Update(ctx, updates)
+		return err
+	}
+	data, paths := updateData(updates)
+	if mode == UpdateModeMerge {
+		_, err := docRef.Set(ctx, data, firestore.Merge(paths...))
+		return err
+	}
+	_, err := docRef.Set(ctx, data)
+	return err
+}
+
+// updateData returns document data of field updates and the updated field paths
+func updateData(updates []firestore.Update) (map[string]interface{}, []firestore.FieldPath) {
+	data := make(map[string]interface{}, len(updates))
+	paths := make([]firestore.FieldPath, 0, len(updates))
+	for _, update := range updates {
+		shared.SetPath(data, update.FieldPath, update.Value)
+		paths = append(paths, update.FieldPath)
+	}
+	return data, paths
+}
+
+// validateUpdateMode checks update mode and field values, DELETE_FIELD() can not be used to replace a document
+func validateUpdateMode(mode string, updates []firestore.Update) error {
+	switch mode {
+	case UpdateModeUpdate, UpdateModeMerge:
+		return nil
+	case UpdateModeReplace:
+		for _, update := range updates {
+			if update.Value == firestore.Delete {
+				return fmt.Errorf("%s() can not be used with %s update mode: %v", deleteFieldFunction, mode, strings.Join(update.FieldPath, "."))
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported update mode: %v", mode)
+}
@@ -0,0 +1,213 @@
+package firestore
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStatement_ExecUpdateFieldPaths(t *testing.T) {
+	var testCases = []struct {
+		description string
+		SQL         string
+		args        []interface{}
+		// expectMask is the update mask received by the server
+		expectMask []string
+		// expectFields are string values of field paths after the update, empty if missing
+		expectFields map[string]string
+	}{
+		{
+			description:  "nested field keeps sibling fields",
+			SQL:          "UPDATE users SET address.city = ? WHERE id = ?",
+			args:         []interface{}{"SF", "u1"},
+			expectMask:   []string{"address.city"},
+			expectFields: map[string]string{"address.city": "SF", "address.zip": "90001", "name": "a"},
+		},
+		{
+			description:  "quoted field path",
+			SQL:          "UPDATE users SET `meta`.`a-b` = ? WHERE id = ?",
+			args:         []interface{}{"y", "u1"},
+			expectMask:   []string{"meta.`a-b`"},
+			expectFields: map[string]string{"meta.`a-b`": "y", "address.city": "LA"},
+		},
+		{
+			description:  "new nested field",
+			SQL:          "UPDATE users SET address.state = ?, name = ? WHERE name = ?",
+			args:         []interface{}{"CA", "b", "a"},
+			expectMask:   []string{"address.state", "name"},
+			expectFields: map[string]string{"address.state": "CA", "address.city": "LA", "name": "b"},
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, "")
+		fake.putData("users/u1", map[string]interface{}{"name": "a", "address": map[string]interface{}{"city": "LA", "zip": "90001"}, "meta": map[string]interface{}{"a-b": "x"}})
+		if _, err := db.Exec(testCase.SQL, testCase.args...); err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if len(fake.writes) != 1 {
+			t.Errorf("%v: expected 1 write, but had %v", testCase.description, len(fake.writes))
+			continue
+		}
+		mask := fake.writes[0].GetUpdateMask().GetFieldPaths()
+		sort.Strings(mask)
+		if !reflect.DeepEqual(mask, testCase.expectMask) {
+			t.Errorf("%v: expected mask %v, but had %v", testCase.description, testCase.expectMask, mask)
+		}
+		doc := fake.docs[fakeDocumentName("users/u1")]
+		for field, expect := range testCase.expectFields {
+			if actual := lookupField(doc.Fields, fakeFieldPath(field)).GetStringValue(); actual != expect {
+				t.Errorf("%v: expected %v %q, but had %q", testCase.description, field, expect, actual)
+			}
+		}
+	}
+}
+
+func TestStatement_ExecUpdateMode(t *testing.T) {
+	var testCases = []struct {
+		description string
+		DSN         string
+		SQL         string
+		args        []interface{}
+		// expectKind is the kind of the write received by the server: update (mask with exists precondition), merge (mask) or replace
+		expectKind string
+		expectMask []string
+		// expectDeleted are masked fields sent without a value
+		expectDeleted []string
+		expectDocs    map[string]map[string]string
+		expectError   bool
+	}{
+		{
+			description:   "unset",
+			SQL:           "UPDATE users UNSET legacyId WHERE id = ?",
+			args:          []interface{}{"u1"},
+			expectKind:    "update",
+			expectMask:    []string{"legacyId"},
+			expectDeleted: []string{"legacyId"},
+			expectDocs:    map[string]map[string]string{"users/u1": {"name": "a", "plan": "free"}},
+		},
+		{
+			description:   "set and unset",
+			SQL:           "UPDATE users SET plan = ? UNSET legacyId, name WHERE plan = ?",
+			args:          []interface{}{"pro", "free"},
+			expectKind:    "update",
+			expectMask:    []string{"legacyId", "name", "plan"},
+			expectDeleted: []string{"legacyId", "name"},
+			expectDocs:    map[string]map[string]string{"users/u1": {"plan": "pro"}},
+		},
+		{
+			description:   "delete field",
+			SQL:           "UPDATE users SET legacyId = DELETE_FIELD() WHERE id = ?",
+			args:          []interface{}{"u1"},
+			expectKind:    "update",
+			expectMask:    []string{"legacyId"},
+			expectDeleted: []string{"legacyId"},
+			expectDocs:    map[string]map[string]string{"users/u1": {"name": "a", "plan": "free"}},
+		},
+		{
+			description: "update of a missing document",
+			SQL:         "UPDATE users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u2"},
+			expectKind:  "update",
+			expectMask:  []string{"plan"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "free", "legacyId": "x"}},
+			expectError: true,
+		},
+		{
+			description: "merge hint creates a missing document",
+			SQL:         "UPDATE /*+ MERGE */ users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u2"},
+			expectKind:  "merge",
+			expectMask:  []string{"plan"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "free", "legacyId": "x"}, "users/u2": {"plan": "pro"}},
+		},
+		{
+			description:   "merge hint with delete field",
+			SQL:           "UPDATE /*+ MERGE */ users SET plan = ?, legacyId = DELETE_FIELD() WHERE id = ?",
+			args:          []interface{}{"pro", "u1"},
+			expectKind:    "merge",
+			expectMask:    []string{"legacyId", "plan"},
+			expectDeleted: []string{"legacyId"},
+			expectDocs:    map[string]map[string]string{"users/u1": {"name": "a", "plan": "pro"}},
+		},
+		{
+			description: "merge mode of DSN",
+			DSN:         "?updateMode=merge",
+			SQL:         "UPDATE users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u2"},
+			expectKind:  "merge",
+			expectMask:  []string{"plan"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "free", "legacyId": "x"}, "users/u2": {"plan": "pro"}},
+		},
+		{
+			description: "update hint overrides merge mode of DSN",
+			DSN:         "?updateMode=merge",
+			SQL:         "UPDATE /*+ UPDATE */ users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u2"},
+			expectKind:  "update",
+			expectMask:  []string{"plan"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "free", "legacyId": "x"}},
+			expectError: true,
+		},
+		{
+			description: "replace hint",
+			SQL:         "UPDATE /*+ REPLACE */ users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u1"},
+			expectKind:  "replace",
+			expectDocs:  map[string]map[string]string{"users/u1": {"plan": "pro"}},
+		},
+		{
+			description: "merge hint overrides replace mode of DSN",
+			DSN:         "?updateMode=replace",
+			SQL:         "UPDATE /*+ MERGE */ users SET plan = ? WHERE id = ?",
+			args:        []interface{}{"pro", "u1"},
+			expectKind:  "merge",
+			expectMask:  []string{"plan"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "pro", "legacyId": "x"}},
+		},
+		{
+			description: "unset with replace mode of DSN",
+			DSN:         "?updateMode=replace",
+			SQL:         "UPDATE users UNSET legacyId WHERE id = ?",
+			args:        []interface{}{"u1"},
+			expectDocs:  map[string]map[string]string{"users/u1": {"name": "a", "plan": "free", "legacyId": "x"}},
+			expectError: true,
+		},
+	}
+	for _, testCase := range testCases {
+		fake, db := newFakeDB(t, testCase.DSN)
+		fake.put("users/u1", map[string]string{"name": "a", "plan": "free", "legacyId": "x"})
+		if _, err := db.Exec(testCase.SQL, testCase.args...); (err != nil) != testCase.expectError {
+			t.Errorf("%v: expected error %v, but had %v", testCase.description, testCase.expectError, err)
+		}
+		if testCase.expectKind == "" {
+			if len(fake.writes) != 0 {
+				t.Errorf("%v: expected no writes, but had %v", testCase.description, len(fake.writes))
+			}
+		} else if len(fake.writes) != 1 {
+			t.Errorf("%v: expected 1 write, but had %v", testCase.description, len(fake.writes))
+		} else {
+			write := fake.writes[0]
+			kind := "replace"
+			if write.GetUpdateMask() != nil {
+				kind = "merge"
+				if write.GetCurrentDocument().GetExists() {
+					kind = "update"
+				}
+			}
+			mask := write.GetUpdateMask().GetFieldPaths()
+			sort.Strings(mask)
+			if kind != testCase.expectKind || !reflect.DeepEqual(mask, testCase.expectMask) {
+				t.Errorf("%v: expected %v write of %v, but had %v write of %v", testCase.description, testCase.expectKind, testCase.expectMask, kind, mask)
+			}
+			for _, field := range testCase.expectDeleted {
+				if value := lookupField(write.GetUpdate().GetFields(), fakeFieldPath(field)); value != nil {
+					t.Errorf("%v: expected deleted field %v without value, but had %v", testCase.description, field, value)
+				}
+			}
+		}
+		if docs := fake.snapshot(); !reflect.DeepEqual(docs, testCase.expectDocs) {
+			t.Errorf("%v: expected documents %v, but had %v", testCase.description, testCase.expectDocs, docs)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package realtime
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+
+	"firebase.google.com/go/db"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/query"
+)
+
+// queryAggregate fetches matched records and computes GROUP BY, aggregate functions and HAVING clause client side
+func (s *Statement) queryAggregate(ctx context.Context, queryRef *db.Query, selectStmt *query.Select, args []driver.NamedValue) (driver.Rows, error) {
+	var result interface{}
+	if err := queryRef.Get(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to get data: %v", err)
+	}
+	// Records are ordered by key, groups follow the first record of each group
+	var records []map[string]interface{}
+	if data, ok := result.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			records = append(records, recordMap(data[key], key))
+		}
+	}
+	argValues := convertNamedValuesToInterfaceSlice(args)
+	if selectStmt.Qualify != nil {
+		count := shared.Placeholders(selectStmt.Qualify.X)
+		if count > len(argValues) {
+			count = len(argValues)
+		}
+		argValues = argValues[count:]
+	}
+	results, err := shared.Aggregate(selectStmt, records, argValues)
+	if err != nil {
+		return nil, err
+	}
+	if results, err = aggregateWindow(results, selectStmt); err != nil {
+		return nil, err
+	}
+
+	rows := &Rows{index: -1}
+	for _, item := range selectStmt.List {
+		rows.columns = append(rows.columns, shared.ItemName(item))
+	}
+	for _, record := range results {
+		values := make([]interface{}, len(rows.columns))
+		for i, column := range rows.columns {
+			values[i] = record[column]
+		}
+		rows.values = append(rows.values, values)
+	}
+	return rows, nil
+}
+
+// aggregateWindow applies LIMIT and OFFSET to aggregated rows
+func aggregateWindow(results []map[string]interface{}, selectStmt *query.Select) ([]map[string]interface{}, error) {
+	if selectStmt.Offset != nil {
+		offset, err := windowValue(selectStmt.Offset, "OFFSET")
+		if err != nil {
+			return nil, err
+		}
+		if offset >= len(results) {
+			return nil, nil
+		}
+		results = results[offset:]
+	}
+	if selectStmt.Limit != nil {
+		limit, err := windowValue(selectStmt.Limit, "LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		if limit < len(results) {
+			results = results[:limit]
+		}
+	}
+	return results, nil
+}
+
+func windowValue(literal *expr.Literal, clause string) (int, error) {
+	value, err := parseLiteralValue(literal)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %v value: %v", clause, err)
+	}
+	result, ok := value.(int)
+	if !ok || result < 0 {
+		return 0, fmt.Errorf("%v value is not a non-negative integer", clause)
+	}
+	return result, nil
+}
@@ -31,6 +31,11 @@ func (s *Statement) querySelect(ctx context.Context, args []driver.NamedValue) (
 		return nil, err
 	}
 
+	// GROUP BY and aggregate functions are computed client side, LIMIT applies to aggregated rows
+	if shared.IsAggregate(selectStmt) {
+		return s.queryAggregate(ctx, queryRef, selectStmt, args)
+	}
+
 	// Apply LIMIT and OFFSET for pagination
 	queryRef, err = applyLimitOffset(queryRef, selectStmt)
 	if err != nil {
@@ -44,9 +49,7 @@ func (s *Statement) querySelect(ctx context.Context, args []driver.NamedValue) (
 	}
 
 	// Convert result to rows
-	rows := NewRows(result, selectStmt)
-
-	return rows, nil
+	return NewRows(result, selectStmt)
 }
 func applyWhereClause(ref *db.Ref, qualify *expr.Qualify, args []driver.NamedValue) (*db.Query, error) {
 	if qualify == nil || qualify.X == nil {
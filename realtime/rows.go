@@ -2,10 +2,13 @@ package realtime
 
 import (
 	"database/sql/driver"
-	"github.com/viant/sqlparser"
+	"fmt"
+	"github.com/viant/firebase/shared"
+	"github.com/viant/sqlparser/node"
 	"github.com/viant/sqlparser/query"
 	"io"
 	"reflect"
+	"sort"
 )
 
 // Rows implements the driver.Rows interface
@@ -15,30 +18,34 @@ type Rows struct {
 	index   int
 }
 
-// NewRows creates a new Rows instance from Firebase data
-func NewRows(data interface{}, selectStmt *query.Select) *Rows {
+// NewRows creates a new Rows instance from Firebase data, records are ordered by key
+func NewRows(data interface{}, selectStmt *query.Select) (*Rows, error) {
 
 	rows := &Rows{
 		index: -1,
 	}
 
-	var columnsSet bool
-
 	switch records := data.(type) {
 	case map[string]interface{}:
-		for key, record := range records {
-			rowValues, columns := extractRowValuesWithKey(record, key, selectStmt)
-			if !columnsSet {
-				rows.columns = columns
-				columnsSet = true
+		keys := make([]string, 0, len(records))
+		for key := range records {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var expressions []node.Node
+		rows.columns, expressions = extractColumns(records, selectStmt)
+		for _, key := range keys {
+			values, err := extractRowValuesWithKey(records[key], key, rows.columns, expressions)
+			if err != nil {
+				return nil, err
 			}
-			rows.values = append(rows.values, rowValues)
+			rows.values = append(rows.values, values)
 		}
 	default:
 		// Handle other data types as needed
 	}
 
-	return rows
+	return rows, nil
 }
 
 // Columns returns the names of the columns
@@ -88,42 +95,50 @@ func (r *Rows) ColumnTypeNullable(index int) (nullable, ok bool) {
 	return true, true
 }
 
-// Helper function to extract row values from a record with its key
-func extractRowValuesWithKey(record interface{}, key string, selectStmt *query.Select) ([]interface{}, []string) {
-	rowValues := []interface{}{}
-	columns := []string{}
-	recMap, ok := record.(map[string]interface{})
-	if !ok {
-		// So record is primitive, e.g., float64, string, etc.
-		// In this case, we can consider the key as the column name and record as the value
-		if len(selectStmt.List) == 0 || selectStmt.List.IsStarExpr() {
-			rowValues = append(rowValues, record)
-			columns = append(columns, key)
-		} else {
-			for _, item := range selectStmt.List {
-				colName := sqlparser.Stringify(item.Expr)
-				if colName == key {
-					rowValues = append(rowValues, record)
-					columns = append(columns, colName)
-				}
-				// else, no matching column
-			}
+// extractColumns returns select list columns named by alias, field path or SQL text with their expressions,
+// SELECT * columns are the union of record fields in alphabetical order, a primitive record contributes its key
+func extractColumns(records map[string]interface{}, selectStmt *query.Select) ([]string, []node.Node) {
+	if len(selectStmt.List) > 0 && !selectStmt.List.IsStarExpr() {
+		columns := make([]string, len(selectStmt.List))
+		expressions := make([]node.Node, len(selectStmt.List))
+		for i, item := range selectStmt.List {
+			columns[i] = shared.ItemName(item)
+			expressions[i] = shared.Normalize(item.Expr)
 		}
-	} else {
-		// handle recMap
-		if len(selectStmt.List) == 0 || selectStmt.List.IsStarExpr() {
-			for k, v := range recMap {
-				rowValues = append(rowValues, v)
-				columns = append(columns, k)
-			}
-		} else {
-			for _, item := range selectStmt.List {
-				colName := sqlparser.Stringify(item.Expr)
-				val := recMap[colName]
-				rowValues = append(rowValues, val)
-				columns = append(columns, colName)
-			}
+		return columns, expressions
+	}
+	var fields = make([]map[string]interface{}, 0, len(records))
+	for key, record := range records {
+		fields = append(fields, recordMap(record, key))
+	}
+	return shared.StarColumns(fields), nil
+}
+
+// recordMap returns record fields, a primitive record is keyed by its key
+func recordMap(record interface{}, key string) map[string]interface{} {
+	if recMap, ok := record.(map[string]interface{}); ok {
+		return recMap
+	}
+	// So record is primitive, e.g., float64, string, etc.
+	// In this case, we can consider the key as the column name and record as the value
+	return map[string]interface{}{key: record}
+}
+
+// Helper function to extract row values of columns from a record with its key, select list expressions
+// are evaluated against record fields, missing values are nil
+func extractRowValuesWithKey(record interface{}, key string, columns []string, expressions []node.Node) ([]interface{}, error) {
+	rowValues := make([]interface{}, len(columns))
+	recMap := recordMap(record, key)
+	for i, column := range columns {
+		if expressions == nil {
+			rowValues[i] = recMap[column]
+			continue
+		}
+		value, err := shared.Evaluate(expressions[i], recMap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate column %v: %v", column, err)
 		}
+		rowValues[i] = value
 	}
-	return rowValues, columns
+	return rowValues, nil
 }
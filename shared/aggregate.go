@@ -0,0 +1,337 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+	"github.com/viant/sqlparser/query"
+)
+
+// aggregateFunctions represents supported aggregate functions
+var aggregateFunctions = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+// IsAggregate returns true if select statement has GROUP BY clause or aggregate functions in the select list
+func IsAggregate(selectStmt *query.Select) bool {
+	if len(selectStmt.GroupBy) > 0 {
+		return true
+	}
+	for _, item := range selectStmt.List {
+		if len(aggregateCalls(item.Expr)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ItemName returns result column name of a select list item: its alias, field path or SQL text
+func ItemName(item *query.Item) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	if aSwitch, ok := item.Expr.(*expr.Switch); ok {
+		return aSwitch.Raw
+	}
+	return ColumnName(sqlparser.Stringify(item.Expr))
+}
+
+// Aggregate groups records by GROUP BY expressions and computes aggregate functions (COUNT, SUM, AVG, MIN, MAX, COUNT(DISTINCT))
+// of the select list, HAVING clause and ORDER BY clause. Groups not matching HAVING are removed, the remaining ones are ordered by ORDER BY.
+// Each result holds select list values keyed by ItemName, grouped fields at their field path and aggregates keyed by SQL text.
+// havingArgs are bound to HAVING clause placeholders.
+func Aggregate(selectStmt *query.Select, records []map[string]interface{}, havingArgs []interface{}) ([]map[string]interface{}, error) {
+	if selectStmt.List.IsStarExpr() {
+		return nil, fmt.Errorf("SELECT * is not supported with GROUP BY or aggregate functions")
+	}
+	var having node.Node
+	if selectStmt.Having != nil && selectStmt.Having.X != nil {
+		argIndex := 0
+		var err error
+		if having, err = Bind(Normalize(selectStmt.Having.X), havingArgs, &argIndex); err != nil {
+			return nil, fmt.Errorf("could not resolve value in HAVING clause: %v", err)
+		}
+	}
+
+	var calls []*expr.Call
+	for _, item := range selectStmt.List {
+		calls = append(calls, aggregateCalls(item.Expr)...)
+	}
+	calls = append(calls, aggregateCalls(having)...)
+	for _, item := range selectStmt.OrderBy {
+		calls = append(calls, aggregateCalls(item.Expr)...)
+	}
+	aggregators := make([]*aggregator, 0, len(calls))
+	for _, call := range calls {
+		anAggregator, err := newAggregator(call)
+		if err != nil {
+			return nil, err
+		}
+		aggregators = append(aggregators, anAggregator)
+	}
+
+	var groups []*group
+	var index = make(map[string]*group)
+	for _, record := range records {
+		key := strings.Builder{}
+		for _, item := range selectStmt.GroupBy {
+			value, err := Evaluate(Normalize(item.Expr), record)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate GROUP BY %s: %v", sqlparser.Stringify(item.Expr), err)
+			}
+			key.WriteString(fmt.Sprintf("%T:%v|", value, value))
+		}
+		aGroup, ok := index[key.String()]
+		if !ok {
+			aGroup = &group{record: record, states: make([]*aggregateState, len(aggregators))}
+			for i := range aggregators {
+				aGroup.states[i] = &aggregateState{}
+			}
+			index[key.String()] = aGroup
+			groups = append(groups, aGroup)
+		}
+		for i, anAggregator := range aggregators {
+			if err := anAggregator.add(aGroup.states[i], record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(groups) == 0 && len(selectStmt.GroupBy) == 0 {
+		// aggregates without GROUP BY always produce a single row
+		groups = append(groups, &group{record: map[string]interface{}{}, states: make([]*aggregateState, len(aggregators))})
+		for i := range aggregators {
+			groups[0].states[i] = &aggregateState{}
+		}
+	}
+
+	var results = make([]map[string]interface{}, 0, len(groups))
+	for _, aGroup := range groups {
+		result := make(map[string]interface{})
+		for i, anAggregator := range aggregators {
+			result[anAggregator.name] = anAggregator.value(aGroup.states[i])
+		}
+		for _, item := range selectStmt.List {
+			value, err := Evaluate(Normalize(item.Expr), mergeRecords(result, aGroup.record))
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate %s: %v", sqlparser.Stringify(item.Expr), err)
+			}
+			switch item.Expr.(type) {
+			case *expr.Ident, *expr.Selector:
+				SetPath(result, FieldPath(sqlparser.Stringify(item.Expr)), value)
+			}
+			result[ItemName(item)] = value
+		}
+		if having != nil {
+			matched, err := Matches(having, mergeRecords(result, aGroup.record))
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate HAVING clause: %v", err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		results = append(results, result)
+	}
+	SortRecords(results, selectStmt.OrderBy)
+	return results, nil
+}
+
+// SortRecords sorts records by ORDER BY clause
+func SortRecords(records []map[string]interface{}, orderBy query.List) {
+	if len(orderBy) == 0 {
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, item := range orderBy {
+			x, _ := Evaluate(Normalize(item.Expr), records[i])
+			y, _ := Evaluate(Normalize(item.Expr), records[j])
+			diff := Compare(x, y)
+			if diff == 0 {
+				continue
+			}
+			if strings.EqualFold(item.Direction, "DESC") {
+				return diff > 0
+			}
+			return diff < 0
+		}
+		return false
+	})
+}
+
+// SetPath sets nested field value creating intermediate maps
+func SetPath(record map[string]interface{}, path []string, value interface{}) {
+	for _, name := range path[:len(path)-1] {
+		child, ok := record[name].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			record[name] = child
+		}
+		record = child
+	}
+	record[path[len(path)-1]] = value
+}
+
+// group represents records sharing GROUP BY values
+type group struct {
+	record map[string]interface{}
+	states []*aggregateState
+}
+
+// aggregateState represents intermediate aggregate function state of a group
+type aggregateState struct {
+	count    int64
+	sum      interface{}
+	extremum interface{}
+	distinct map[string]bool
+}
+
+// aggregator computes an aggregate function
+type aggregator struct {
+	name     string
+	function string
+	arg      node.Node
+	distinct bool
+}
+
+func newAggregator(call *expr.Call) (*aggregator, error) {
+	result := &aggregator{name: sqlparser.Stringify(call), function: strings.ToUpper(sqlparser.Stringify(call.X))}
+	raw := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(call.Raw), "("), ")"))
+	if len(raw) > len("DISTINCT ") && strings.EqualFold(raw[:len("DISTINCT ")], "DISTINCT ") {
+		arg, err := parseExpression(raw[len("DISTINCT "):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", result.name, err)
+		}
+		result.arg = arg
+		result.distinct = true
+		return result, nil
+	}
+	if len(call.Args) != 1 {
+		return nil, fmt.Errorf("%s expects a single argument", result.name)
+	}
+	if _, ok := call.Args[0].(*expr.Star); !ok {
+		result.arg = Normalize(call.Args[0])
+	} else if result.function != "COUNT" {
+		return nil, fmt.Errorf("invalid %s argument", result.name)
+	}
+	return result, nil
+}
+
+// add accumulates record into aggregate state
+func (a *aggregator) add(state *aggregateState, record map[string]interface{}) error {
+	if a.arg == nil {
+		state.count++
+		return nil
+	}
+	value, err := Evaluate(a.arg, record)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate %s: %v", a.name, err)
+	}
+	if value == nil {
+		return nil
+	}
+	if a.distinct {
+		key := fmt.Sprintf("%T:%v", value, value)
+		if state.distinct == nil {
+			state.distinct = make(map[string]bool)
+		}
+		if state.distinct[key] {
+			return nil
+		}
+		state.distinct[key] = true
+	}
+	state.count++
+	switch a.function {
+	case "SUM", "AVG":
+		if state.sum == nil {
+			state.sum = int64(0)
+		}
+		if state.sum, err = arithmetic("+", state.sum, value); err != nil {
+			return fmt.Errorf("failed to compute %s: %v", a.name, err)
+		}
+	case "MIN":
+		if state.extremum == nil || Compare(value, state.extremum) < 0 {
+			state.extremum = value
+		}
+	case "MAX":
+		if state.extremum == nil || Compare(value, state.extremum) > 0 {
+			state.extremum = value
+		}
+	}
+	return nil
+}
+
+// value returns aggregate function result: SUM of no values is 0 as Firestore aggregation query returns,
+// NULL is returned for AVG, MIN and MAX of no values
+func (a *aggregator) value(state *aggregateState) interface{} {
+	switch a.function {
+	case "COUNT":
+		return state.count
+	case "SUM":
+		if state.sum == nil {
+			return int64(0)
+		}
+		return state.sum
+	case "AVG":
+		if state.count == 0 {
+			return nil
+		}
+		sum, _ := AsFloat64(state.sum)
+		return sum / float64(state.count)
+	}
+	return state.extremum
+}
+
+// aggregateCalls returns aggregate function calls of an expression
+func aggregateCalls(n node.Node) []*expr.Call {
+	var result []*expr.Call
+	switch actual := n.(type) {
+	case *expr.Call:
+		if aggregateFunctions[strings.ToUpper(sqlparser.Stringify(actual.X))] {
+			return []*expr.Call{actual}
+		}
+		for _, arg := range actual.Args {
+			result = append(result, aggregateCalls(arg)...)
+		}
+	case *expr.Binary:
+		result = append(aggregateCalls(actual.X), aggregateCalls(actual.Y)...)
+	case *expr.Unary:
+		result = aggregateCalls(actual.X)
+	case *expr.Parenthesis:
+		if inner, ok := actual.X.(node.Node); ok {
+			result = aggregateCalls(inner)
+		}
+	}
+	return result
+}
+
+// parseExpression parses a standalone SQL expression
+func parseExpression(expression string) (node.Node, error) {
+	aQuery, err := sqlparser.ParseQuery("SELECT " + expression + " FROM t")
+	if err != nil {
+		return nil, err
+	}
+	if len(aQuery.List) != 1 {
+		return nil, fmt.Errorf("invalid expression: %s", expression)
+	}
+	return Normalize(aQuery.List[0].Expr), nil
+}
+
+// mergeRecords returns a record with fields of primary overriding fields of secondary
+func mergeRecords(primary, secondary map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(primary)+len(secondary))
+	for k, v := range secondary {
+		result[k] = v
+	}
+	for k, v := range primary {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/viant/sqlparser"
+)
+
+func TestAggregate(t *testing.T) {
+	records := []map[string]interface{}{
+		{"region": "us", "user": "a", "amount": int64(3), "address": map[string]interface{}{"city": "LA"}},
+		{"region": "us", "user": "a", "amount": 2.5, "address": map[string]interface{}{"city": "SF"}},
+		{"region": "eu", "user": "b", "amount": int64(1), "address": map[string]interface{}{"city": "PA"}},
+		{"region": "us", "user": "c", "amount": nil, "address": map[string]interface{}{"city": "LA"}},
+	}
+	var testCases = []struct {
+		description string
+		SQL         string
+		records     []map[string]interface{}
+		args        []interface{}
+		expect      []map[string]interface{}
+	}{
+		{
+			description: "aggregate functions by group",
+			SQL:         "SELECT region, COUNT(DISTINCT user) AS u, COUNT(*) AS c, SUM(amount) AS s, AVG(amount) AS a, MIN(address.city) AS mn, MAX(amount) AS mx FROM t GROUP BY region ORDER BY region",
+			records:     records,
+			expect: []map[string]interface{}{
+				{"region": "eu", "u": int64(1), "c": int64(1), "s": int64(1), "a": 1.0, "mn": "PA", "mx": int64(1)},
+				{"region": "us", "u": int64(2), "c": int64(3), "s": 5.5, "a": 2.75, "mn": "LA", "mx": int64(3)},
+			},
+		},
+		{
+			description: "HAVING with placeholder",
+			SQL:         "SELECT region, COUNT(*) AS c FROM t GROUP BY region HAVING COUNT(*) > ?",
+			records:     records,
+			args:        []interface{}{1},
+			expect:      []map[string]interface{}{{"region": "us", "c": int64(3)}},
+		},
+		{
+			description: "nested GROUP BY field ordered by aggregate",
+			SQL:         "SELECT address.city, COUNT(amount) AS c FROM t GROUP BY address.city ORDER BY c DESC, address.city",
+			records:     records,
+			expect: []map[string]interface{}{
+				{"address.city": "LA", "c": int64(1)},
+				{"address.city": "PA", "c": int64(1)},
+				{"address.city": "SF", "c": int64(1)},
+			},
+		},
+		{
+			description: "groups without ORDER BY follow their first record",
+			SQL:         "SELECT address.city, COUNT(*) AS c FROM t GROUP BY address.city",
+			records:     records,
+			expect: []map[string]interface{}{
+				{"address.city": "LA", "c": int64(2)},
+				{"address.city": "SF", "c": int64(1)},
+				{"address.city": "PA", "c": int64(1)},
+			},
+		},
+		{
+			description: "no records",
+			SQL:         "SELECT COUNT(*) AS c, SUM(amount) AS s, AVG(amount) AS a, MAX(amount) AS mx FROM t",
+			expect:      []map[string]interface{}{{"c": int64(0), "s": int64(0), "a": nil, "mx": nil}},
+		},
+	}
+	for _, testCase := range testCases {
+		selectStmt, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.description, err)
+		}
+		if !IsAggregate(selectStmt) {
+			t.Errorf("%v: expected aggregate statement", testCase.description)
+		}
+		results, err := Aggregate(selectStmt, testCase.records, testCase.args)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		var actual []map[string]interface{}
+		for _, result := range results {
+			row := map[string]interface{}{}
+			for _, item := range selectStmt.List {
+				name := ItemName(item)
+				row[name] = Lookup(result, name)
+			}
+			actual = append(actual, row)
+		}
+		if !reflect.DeepEqual(actual, testCase.expect) {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package shared
+
+import (
+	"fmt"
+
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+// Bound represents a placeholder bound to an argument value
+type Bound struct {
+	Value interface{}
+}
+
+// Bind returns a copy of the expression with placeholders replaced by bound argument values,
+// placeholders are bound left to right starting from argIndex
+func Bind(n node.Node, args []interface{}, argIndex *int) (node.Node, error) {
+	switch actual := n.(type) {
+	case *expr.Placeholder:
+		if *argIndex >= len(args) {
+			return nil, fmt.Errorf("not enough arguments provided")
+		}
+		value := args[*argIndex]
+		*argIndex++
+		return &Bound{Value: value}, nil
+	case *expr.Binary:
+		x, err := Bind(actual.X, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		y, err := Bind(actual.Y, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Binary{X: x, Op: actual.Op, Y: y}, nil
+	case *expr.Unary:
+		x, err := Bind(actual.X, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Unary{Op: actual.Op, X: x}, nil
+	case *expr.Parenthesis:
+		x, err := Bind(actual.X, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Parenthesis{Raw: actual.Raw, X: x}, nil
+	case []node.Node:
+		items, err := bindList(actual, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return items, nil
+	case *expr.Range:
+		min, err := Bind(actual.Min, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		max, err := Bind(actual.Max, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Range{Min: min, Max: max}, nil
+	case *expr.Call:
+		items, err := bindList(actual.Args, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Call{X: actual.X, Raw: actual.Raw, Args: items}, nil
+	case *expr.Switch:
+		aCase, err := parseCase(actual.Raw)
+		if err != nil {
+			return nil, err
+		}
+		return aCase.bind(args, argIndex)
+	}
+	return n, nil
+}
+
+// bind returns a copy of CASE expression with placeholders bound in SQL text order
+func (aCase *caseExpr) bind(args []interface{}, argIndex *int) (*caseExpr, error) {
+	result := &caseExpr{whens: make([]node.Node, len(aCase.whens)), thens: make([]node.Node, len(aCase.thens))}
+	var err error
+	if aCase.operand != nil {
+		if result.operand, err = Bind(aCase.operand, args, argIndex); err != nil {
+			return nil, err
+		}
+	}
+	for i := range aCase.whens {
+		if result.whens[i], err = Bind(aCase.whens[i], args, argIndex); err != nil {
+			return nil, err
+		}
+		if result.thens[i], err = Bind(aCase.thens[i], args, argIndex); err != nil {
+			return nil, err
+		}
+	}
+	if aCase.orElse != nil {
+		if result.orElse, err = Bind(aCase.orElse, args, argIndex); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func bindList(list []node.Node, args []interface{}, argIndex *int) ([]node.Node, error) {
+	result := make([]node.Node, len(list))
+	for i, item := range list {
+		bound, err := Bind(item, args, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = bound
+	}
+	return result, nil
+}
+
+// Placeholders returns number of placeholders of the expression
+func Placeholders(n node.Node) int {
+	switch actual := n.(type) {
+	case *expr.Placeholder:
+		return 1
+	case *expr.Binary:
+		return Placeholders(actual.X) + Placeholders(actual.Y)
+	case *expr.Unary:
+		return Placeholders(actual.X)
+	case *expr.Parenthesis:
+		return Placeholders(actual.X)
+	case []node.Node:
+		count := 0
+		for _, item := range actual {
+			count += Placeholders(item)
+		}
+		return count
+	case *expr.Range:
+		return Placeholders(actual.Min) + Placeholders(actual.Max)
+	case *expr.Call:
+		return Placeholders(actual.Args)
+	case *expr.Switch:
+		aCase, err := parseCase(actual.Raw)
+		if err != nil {
+			return 0
+		}
+		return Placeholders(aCase.operand) + Placeholders(aCase.whens) + Placeholders(aCase.thens) + Placeholders(aCase.orElse)
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/viant/sqlparser"
+)
+
+func TestBind(t *testing.T) {
+	record := map[string]interface{}{"name": "x", "age": int64(5)}
+	var testCases = []struct {
+		description string
+		where       string
+		args        []interface{}
+		expect      bool
+	}{
+		{
+			description: "binary",
+			where:       "age + ? > ?",
+			args:        []interface{}{1, 5},
+			expect:      true,
+		},
+		{
+			description: "call and list",
+			where:       "COALESCE(missing, ?) IN (?, ?)",
+			args:        []interface{}{"a", "b", "a"},
+			expect:      true,
+		},
+		{
+			description: "searched case binds in SQL order",
+			where:       "CASE WHEN name = ? THEN ? WHEN age > ? THEN ? ELSE ? END = ?",
+			args:        []interface{}{"y", "first", 3, "second", "other", "second"},
+			expect:      true,
+		},
+		{
+			description: "simple case",
+			where:       "CASE name WHEN ? THEN ? ELSE ? END = ?",
+			args:        []interface{}{"x", 1, 0, 1},
+			expect:      true,
+		},
+		{
+			description: "simple case else",
+			where:       "CASE name WHEN ? THEN ? ELSE ? END = ?",
+			args:        []interface{}{"z", 1, 0, 1},
+			expect:      false,
+		},
+		{
+			description: "case within conjunction",
+			where:       "name = ? AND CASE WHEN age > ? THEN 'old' ELSE 'young' END = ?",
+			args:        []interface{}{"x", 3, "old"},
+			expect:      true,
+		},
+	}
+	for _, testCase := range testCases {
+		query, err := sqlparser.ParseQuery("SELECT * FROM t WHERE " + testCase.where)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.description, err)
+		}
+		n := Normalize(query.Qualify.X)
+		if count := Placeholders(n); count != len(testCase.args) {
+			t.Errorf("%v: expected %v placeholders, but had %v", testCase.description, len(testCase.args), count)
+		}
+		argIndex := 0
+		bound, err := Bind(n, testCase.args, &argIndex)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if argIndex != len(testCase.args) {
+			t.Errorf("%v: expected %v bound arguments, but had %v", testCase.description, len(testCase.args), argIndex)
+		}
+		actual, err := Matches(bound, record)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.description, err)
+			continue
+		}
+		if actual != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package shared
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Compare compares two values returning a negative number when x < y, zero when x == y and a positive number otherwise,
+// values of different types are ordered by type the same way Firestore does: null, bool, number, timestamp, string, bytes, others
+func Compare(x, y interface{}) int {
+	xRank, yRank := typeRank(x), typeRank(y)
+	if xRank != yRank {
+		return xRank - yRank
+	}
+	switch xRank {
+	case rankNull:
+		return 0
+	case rankBool:
+		xBool, yBool := x.(bool), y.(bool)
+		switch {
+		case xBool == yBool:
+			return 0
+		case xBool:
+			return 1
+		}
+		return -1
+	case rankNumber:
+		if xInt, ok := asInt64(x); ok {
+			if yInt, ok := asInt64(y); ok {
+				return cmp.Compare(xInt, yInt)
+			}
+		}
+		xFloat, _ := AsFloat64(x)
+		yFloat, _ := AsFloat64(y)
+		return cmp.Compare(xFloat, yFloat)
+	case rankTime:
+		return x.(time.Time).Compare(y.(time.Time))
+	case rankString:
+		return cmp.Compare(x.(string), y.(string))
+	case rankBytes:
+		return bytes.Compare(x.([]byte), y.([]byte))
+	}
+	return cmp.Compare(fmt.Sprintf("%v", x), fmt.Sprintf("%v", y))
+}
+
+// AsFloat64 converts numeric value to float64
+func AsFloat64(value interface{}) (float64, bool) {
+	switch actual := value.(type) {
+	case float64:
+		return actual, true
+	case float32:
+		return float64(actual), true
+	}
+	rValue := reflect.ValueOf(value)
+	switch rValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rValue.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rValue.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rValue.Float(), true
+	}
+	return 0, false
+}
+
+func asInt64(value interface{}) (int64, bool) {
+	rValue := reflect.ValueOf(value)
+	switch rValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rValue.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rValue.Uint()), true
+	}
+	return 0, false
+}
+
+const (
+	rankNull = iota
+	rankBool
+	rankNumber
+	rankTime
+	rankString
+	rankBytes
+	rankOther
+)
+
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return rankNull
+	case bool:
+		return rankBool
+	case time.Time:
+		return rankTime
+	case string:
+		return rankString
+	case []byte:
+		return rankBytes
+	}
+	if _, ok := AsFloat64(value); ok {
+		return rankNumber
+	}
+	return rankOther
+}
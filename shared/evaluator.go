@@ -0,0 +1,384 @@
+package shared
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+// Matches evaluates bound WHERE expression against a record, NULL outcome does not match
+func Matches(n node.Node, record map[string]interface{}) (bool, error) {
+	value, err := Evaluate(n, record)
+	if err != nil {
+		return false, err
+	}
+	matched, _ := value.(bool)
+	return matched, nil
+}
+
+// Evaluate evaluates bound expression against a record, columns are resolved from record fields
+func Evaluate(n node.Node, record map[string]interface{}) (interface{}, error) {
+	switch actual := n.(type) {
+	case *Bound:
+		return actual.Value, nil
+	case *expr.Literal:
+		return ParseLiteral(actual)
+	case *expr.Ident:
+		return Lookup(record, actual.Name), nil
+	case *expr.Selector:
+		return Lookup(record, sqlparser.Stringify(actual)), nil
+	case *expr.Parenthesis:
+		if list, ok := actual.X.([]node.Node); ok {
+			return evaluateList(list, record)
+		}
+		return Evaluate(actual.X, record)
+	case *expr.Unary:
+		if strings.EqualFold(actual.Op, "NOT") {
+			value, err := Evaluate(actual.X, record)
+			if err != nil || value == nil {
+				return nil, err
+			}
+			matched, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("NOT expects boolean operand, but had: %T", value)
+			}
+			return !matched, nil
+		}
+		return nil, fmt.Errorf("unsupported unary operator: %s", actual.Op)
+	case *expr.Binary:
+		return evaluateBinary(actual, record)
+	case *expr.Call:
+		return evaluateCall(actual, record)
+	case *expr.Switch:
+		return evaluateSwitch(actual, record)
+	case *caseExpr:
+		return actual.evaluate(record)
+	case nil:
+		return nil, fmt.Errorf("expression was empty")
+	}
+	return nil, fmt.Errorf("unsupported expression: %s", sqlparser.Stringify(n))
+}
+
+// Lookup returns record field value, a field path (i.e. address.city) walks nested maps
+func Lookup(record map[string]interface{}, column string) interface{} {
+	if value, ok := record[column]; ok {
+		return value
+	}
+	return LookupPath(record, FieldPath(column))
+}
+
+// LookupPath returns nested field value or nil if the path does not exist
+func LookupPath(record map[string]interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+	var current interface{} = record
+	for _, name := range path {
+		aMap, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = aMap[name]
+	}
+	return current
+}
+
+// ParseLiteral converts literal into Go value
+func ParseLiteral(literal *expr.Literal) (interface{}, error) {
+	switch literal.Kind {
+	case "string":
+		value := literal.Value
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, nil
+	case "int":
+		return strconv.ParseInt(literal.Value, 10, 64)
+	case "numeric":
+		if value, err := strconv.ParseInt(literal.Value, 10, 64); err == nil {
+			return value, nil
+		}
+		return strconv.ParseFloat(literal.Value, 64)
+	case "float":
+		return strconv.ParseFloat(literal.Value, 64)
+	case "bool":
+		return strconv.ParseBool(literal.Value)
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported literal kind: %s", literal.Kind)
+}
+
+func evaluateList(list []node.Node, record map[string]interface{}) ([]interface{}, error) {
+	var result = make([]interface{}, 0, len(list))
+	for _, item := range list {
+		value, err := Evaluate(item, record)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ExpandSlice(value)...)
+	}
+	return result, nil
+}
+
+func evaluateBinary(binary *expr.Binary, record map[string]interface{}) (interface{}, error) {
+	switch binary.Op {
+	case "AND", "OR":
+		return evaluateLogical(binary, record)
+	case "BETWEEN":
+		aRange, ok := binary.Y.(*expr.Range)
+		if !ok {
+			return nil, fmt.Errorf("invalid BETWEEN range: %s", sqlparser.Stringify(binary))
+		}
+		value, err := Evaluate(binary.X, record)
+		if err != nil {
+			return nil, err
+		}
+		min, err := Evaluate(aRange.Min, record)
+		if err != nil {
+			return nil, err
+		}
+		max, err := Evaluate(aRange.Max, record)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil || min == nil || max == nil {
+			return nil, nil
+		}
+		return Compare(value, min) >= 0 && Compare(value, max) <= 0, nil
+	}
+	if binary.Op == "=" {
+		if call := AnyCall(binary.Y); call != nil {
+			return evaluateArrayContains(call.Args[0], binary.X, record)
+		}
+		if call := AnyCall(binary.X); call != nil {
+			return evaluateArrayContains(call.Args[0], binary.Y, record)
+		}
+	}
+	x, err := Evaluate(binary.X, record)
+	if err != nil {
+		return nil, err
+	}
+	if binary.Op == "IN" || binary.Op == "NOT IN" {
+		return evaluateIn(binary, x, record)
+	}
+	y, err := Evaluate(binary.Y, record)
+	if err != nil {
+		return nil, err
+	}
+	switch binary.Op {
+	case "+", "-", "*", "/", "%":
+		return arithmetic(binary.Op, x, y)
+	case "IS":
+		return x == nil && y == nil, nil
+	case "IS NOT":
+		return (x == nil) != (y == nil), nil
+	}
+	if x == nil || y == nil {
+		return nil, nil
+	}
+	switch binary.Op {
+	case "=":
+		return Compare(x, y) == 0, nil
+	case "!=", "<>":
+		return Compare(x, y) != 0, nil
+	case "<":
+		return Compare(x, y) < 0, nil
+	case "<=":
+		return Compare(x, y) <= 0, nil
+	case ">":
+		return Compare(x, y) > 0, nil
+	case ">=":
+		return Compare(x, y) >= 0, nil
+	case "LIKE":
+		return Like(fmt.Sprintf("%v", x), fmt.Sprintf("%v", y))
+	}
+	return nil, fmt.Errorf("unsupported operator: %s", binary.Op)
+}
+
+func evaluateLogical(binary *expr.Binary, record map[string]interface{}) (interface{}, error) {
+	x, err := Evaluate(binary.X, record)
+	if err != nil {
+		return nil, err
+	}
+	if xBool, ok := x.(bool); ok {
+		if binary.Op == "AND" && !xBool {
+			return false, nil
+		}
+		if binary.Op == "OR" && xBool {
+			return true, nil
+		}
+	}
+	y, err := Evaluate(binary.Y, record)
+	if err != nil {
+		return nil, err
+	}
+	if yBool, ok := y.(bool); ok {
+		if x == nil {
+			if binary.Op == "AND" && !yBool || binary.Op == "OR" && yBool {
+				return yBool, nil
+			}
+			return nil, nil
+		}
+		return yBool, nil
+	}
+	return nil, nil
+}
+
+func evaluateIn(binary *expr.Binary, x interface{}, record map[string]interface{}) (interface{}, error) {
+	var values []interface{}
+	var err error
+	if parenthesis, ok := binary.Y.(*expr.Parenthesis); ok {
+		if list, ok := parenthesis.X.([]node.Node); ok {
+			values, err = evaluateList(list, record)
+		}
+	}
+	if values == nil && err == nil {
+		values, err = evaluateList([]node.Node{binary.Y}, record)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if x == nil {
+		return nil, nil
+	}
+	found := false
+	for _, value := range values {
+		if value != nil && Compare(x, value) == 0 {
+			found = true
+			break
+		}
+	}
+	if binary.Op == "NOT IN" {
+		return !found, nil
+	}
+	return found, nil
+}
+
+func evaluateCall(call *expr.Call, record map[string]interface{}) (interface{}, error) {
+	if value, ok := record[sqlparser.Stringify(call)]; ok { // precomputed value, i.e. an aggregate
+		return value, nil
+	}
+	name := strings.ToUpper(sqlparser.Stringify(call.X))
+	switch name {
+	case "CAST":
+		return evaluateCast(call, record)
+	case "EXTRACT":
+		return evaluateExtract(call, record)
+	case "ARRAY_CONTAINS":
+		if len(call.Args) != 2 {
+			return nil, fmt.Errorf("%s expects column and value arguments", name)
+		}
+		return evaluateArrayContains(call.Args[0], call.Args[1], record)
+	case "ARRAY_CONTAINS_ANY":
+		if len(call.Args) < 2 {
+			return nil, fmt.Errorf("%s expects column and value arguments", name)
+		}
+		array, err := Evaluate(call.Args[0], record)
+		if err != nil {
+			return nil, err
+		}
+		values, err := evaluateList(call.Args[1:], record)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if containsValue(array, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported function: %s", sqlparser.Stringify(call))
+	}
+	args := make([]interface{}, len(call.Args))
+	for i, arg := range call.Args {
+		value, err := Evaluate(arg, record)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return fn(args)
+}
+
+func evaluateArrayContains(column, valueExpr node.Node, record map[string]interface{}) (interface{}, error) {
+	array, err := Evaluate(column, record)
+	if err != nil {
+		return nil, err
+	}
+	value, err := Evaluate(valueExpr, record)
+	if err != nil {
+		return nil, err
+	}
+	return containsValue(array, value), nil
+}
+
+func containsValue(array interface{}, value interface{}) bool {
+	if array == nil {
+		return false
+	}
+	for _, item := range ExpandSlice(array) {
+		if Compare(item, value) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyCall returns ANY(column) call or nil
+func AnyCall(n node.Node) *expr.Call {
+	call, ok := n.(*expr.Call)
+	if !ok || len(call.Args) != 1 || !strings.EqualFold(sqlparser.Stringify(call.X), "ANY") {
+		return nil
+	}
+	return call
+}
+
+var likePatterns sync.Map
+
+// Like returns true if value matches SQL LIKE pattern, where % matches any sequence and _ matches a single character
+func Like(value, pattern string) (bool, error) {
+	compiled, ok := likePatterns.Load(pattern)
+	if !ok {
+		expression := strings.Builder{}
+		expression.WriteString("(?s)^")
+		for _, r := range pattern {
+			switch r {
+			case '%':
+				expression.WriteString(".*")
+			case '_':
+				expression.WriteString(".")
+			default:
+				expression.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		expression.WriteString("$")
+		re, err := regexp.Compile(expression.String())
+		if err != nil {
+			return false, fmt.Errorf("invalid LIKE pattern %v: %v", pattern, err)
+		}
+		compiled, _ = likePatterns.LoadOrStore(pattern, re)
+	}
+	return compiled.(*regexp.Regexp).MatchString(value), nil
+}
+
+// LikePrefix returns prefix of a LIKE pattern in the form 'prefix%' or the pattern itself when it has no wildcards
+func LikePrefix(pattern string) (prefix string, exact bool, ok bool) {
+	wildcard := strings.IndexAny(pattern, "%_")
+	if wildcard == -1 {
+		return pattern, true, true
+	}
+	if wildcard == len(pattern)-1 && pattern[wildcard] == '%' {
+		return pattern[:wildcard], false, true
+	}
+	return "", false, false
+}
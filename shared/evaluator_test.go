@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/viant/sqlparser"
+)
+
+func TestMatches(t *testing.T) {
+	record := map[string]interface{}{
+		"name": "xabcx",
+		"age":  int64(5),
+		"tags": []interface{}{"a", "b"},
+		"m":    map[string]interface{}{"k": 3},
+	}
+	var testCases = []struct {
+		where  string
+		args   []interface{}
+		expect bool
+	}{
+		{where: "name LIKE '%abc%' AND age BETWEEN ? AND ?", args: []interface{}{1, 6}, expect: true},
+		{where: "name LIKE '%abc%' AND age BETWEEN ? AND ?", args: []interface{}{6, 9}, expect: false},
+		{where: "name LIKE 'a_c' OR age IN (?)", args: []interface{}{[]int{4, 5}}, expect: true},
+		{where: "age NOT IN (1, 2) AND name != 'y'", expect: true},
+		{where: "NOT (name LIKE 'x_b%') OR ? = ANY(tags)", args: []interface{}{"c"}, expect: false},
+		{where: "missing = 1 OR m.k >= 3", expect: true},
+		{where: "missing = 1", expect: false},
+		{where: "missing != 1", expect: false},
+		{where: "missing IS NULL AND name IS NOT NULL", expect: true},
+		{where: "LOWER(name) = ? AND age * 2 + 1 = 11 AND LENGTH(tags) = 2", args: []interface{}{"xabcx"}, expect: true},
+		{where: "age / 2 = 2 AND age - 10 < 0 AND COALESCE(missing, 7) = 7", expect: true},
+		{where: "ARRAY_CONTAINS(tags, 'a') AND ARRAY_CONTAINS_ANY(tags, ?)", args: []interface{}{[]string{"z", "b"}}, expect: true},
+	}
+	for _, testCase := range testCases {
+		query, err := sqlparser.ParseQuery("SELECT * FROM t WHERE " + testCase.where)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.where, err)
+		}
+		argIndex := 0
+		bound, err := Bind(Normalize(query.Qualify.X), testCase.args, &argIndex)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.where, err)
+		}
+		actual, err := Matches(bound, record)
+		if err != nil {
+			t.Errorf("%v: %v", testCase.where, err)
+			continue
+		}
+		if actual != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.where, testCase.expect, actual)
+		}
+	}
+}
+
+func TestLike(t *testing.T) {
+	var testCases = []struct {
+		value   string
+		pattern string
+		expect  bool
+	}{
+		{value: "abc", pattern: "abc", expect: true},
+		{value: "abcd", pattern: "abc", expect: false},
+		{value: "abcd", pattern: "abc%", expect: true},
+		{value: "xabcx", pattern: "%abc%", expect: true},
+		{value: "xabx", pattern: "%abc%", expect: false},
+		{value: "abc", pattern: "a_c", expect: true},
+		{value: "abbc", pattern: "a_c", expect: false},
+		{value: "a.c", pattern: "a.c", expect: true},
+		{value: "abc", pattern: "a.c", expect: false},
+		{value: "line\nbreak", pattern: "line%", expect: true},
+		{value: "", pattern: "%", expect: true},
+	}
+	for _, testCase := range testCases {
+		actual, err := Like(testCase.value, testCase.pattern)
+		if err != nil {
+			t.Errorf("%v LIKE %v: %v", testCase.value, testCase.pattern, err)
+			continue
+		}
+		if actual != testCase.expect {
+			t.Errorf("%v LIKE %v: expected %v, but had %v", testCase.value, testCase.pattern, testCase.expect, actual)
+		}
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	var testCases = []struct {
+		pattern      string
+		expectPrefix string
+		expectExact  bool
+		expectOk     bool
+	}{
+		{pattern: "abc", expectPrefix: "abc", expectExact: true, expectOk: true},
+		{pattern: "abc%", expectPrefix: "abc", expectOk: true},
+		{pattern: "%", expectPrefix: "", expectOk: true},
+		{pattern: "%abc", expectOk: false},
+		{pattern: "a%c", expectOk: false},
+		{pattern: "ab_%", expectOk: false},
+		{pattern: "abc%%", expectOk: false},
+	}
+	for _, testCase := range testCases {
+		prefix, exact, ok := LikePrefix(testCase.pattern)
+		if prefix != testCase.expectPrefix || exact != testCase.expectExact || ok != testCase.expectOk {
+			t.Errorf("%v: expected %q, %v, %v, but had %q, %v, %v", testCase.pattern, testCase.expectPrefix, testCase.expectExact, testCase.expectOk, prefix, exact, ok)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+// caseExpr represents parsed CASE [operand] WHEN condition THEN result ... [ELSE result] END expression,
+// Bind returns a caseExpr node of CASE expression with bound placeholders
+type caseExpr struct {
+	operand node.Node
+	whens   []node.Node
+	thens   []node.Node
+	orElse  node.Node
+}
+
+// castExpr represents parsed CAST(expression AS type) or EXTRACT(part FROM expression) call
+type castExpr struct {
+	x        node.Node
+	typeName string
+}
+
+// parsedExpressions caches expressions parsed from raw SQL text keyed by the text
+var parsedExpressions sync.Map
+
+// evaluateSwitch evaluates CASE expression
+func evaluateSwitch(aSwitch *expr.Switch, record map[string]interface{}) (interface{}, error) {
+	aCase, err := parseCase(aSwitch.Raw)
+	if err != nil {
+		return nil, err
+	}
+	return aCase.evaluate(record)
+}
+
+// evaluate evaluates parsed CASE expression
+func (aCase *caseExpr) evaluate(record map[string]interface{}) (interface{}, error) {
+	var operand interface{}
+	var err error
+	if aCase.operand != nil {
+		if operand, err = Evaluate(aCase.operand, record); err != nil {
+			return nil, err
+		}
+	}
+	for i, when := range aCase.whens {
+		value, err := Evaluate(when, record)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		if aCase.operand != nil {
+			matched = operand != nil && value != nil && Compare(operand, value) == 0
+		} else {
+			matched, _ = value.(bool)
+		}
+		if matched {
+			return Evaluate(aCase.thens[i], record)
+		}
+	}
+	if aCase.orElse != nil {
+		return Evaluate(aCase.orElse, record)
+	}
+	return nil, nil
+}
+
+func parseCase(raw string) (*caseExpr, error) {
+	if cached, ok := parsedExpressions.Load(raw); ok {
+		return cached.(*caseExpr), nil
+	}
+	parts := splitKeywords(raw, "CASE", "WHEN", "THEN", "ELSE", "END")
+	if len(parts) < 2 || parts[0].keyword != "CASE" || parts[len(parts)-1].keyword != "END" {
+		return nil, fmt.Errorf("invalid CASE expression: %s", raw)
+	}
+	aCase := &caseExpr{}
+	var err error
+	if text := parts[0].text; text != "" {
+		if aCase.operand, err = parseExpression(text); err != nil {
+			return nil, fmt.Errorf("invalid CASE operand: %v", err)
+		}
+	}
+	for i := 1; i < len(parts)-1; i++ {
+		part := parts[i]
+		value, err := parseExpression(part.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CASE %s: %v", part.keyword, err)
+		}
+		switch {
+		case part.keyword == "WHEN" && parts[i+1].keyword == "THEN":
+			aCase.whens = append(aCase.whens, value)
+		case part.keyword == "THEN" && parts[i-1].keyword == "WHEN":
+			aCase.thens = append(aCase.thens, value)
+		case part.keyword == "ELSE" && parts[i+1].keyword == "END":
+			aCase.orElse = value
+		default:
+			return nil, fmt.Errorf("invalid CASE expression: %s", raw)
+		}
+	}
+	if len(aCase.whens) == 0 {
+		return nil, fmt.Errorf("invalid CASE expression, WHEN expected: %s", raw)
+	}
+	parsedExpressions.Store(raw, aCase)
+	return aCase, nil
+}
+
+// keywordPart represents SQL text following a keyword
+type keywordPart struct {
+	keyword string
+	text    string
+}
+
+// splitKeywords splits SQL text at top level keywords, text before the first keyword is ignored
+func splitKeywords(text string, keywords ...string) []*keywordPart {
+	positions := keywordPositions(text, keywords...)
+	var result = make([]*keywordPart, len(positions))
+	for i, position := range positions {
+		end := len(text)
+		if i+1 < len(positions) {
+			end = positions[i+1].index
+		}
+		result[i] = &keywordPart{keyword: position.keyword, text: strings.TrimSpace(text[position.index+len(position.keyword) : end])}
+	}
+	return result
+}
+
+// keywordPosition represents a keyword occurrence in SQL text
+type keywordPosition struct {
+	keyword string
+	index   int
+}
+
+// keywordPositions returns occurrences of keywords outside of quotes and parenthesis
+func keywordPositions(text string, keywords ...string) []*keywordPosition {
+	var result []*keywordPosition
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+			continue
+		case c == '(':
+			depth++
+			continue
+		case c == ')':
+			depth--
+			continue
+		}
+		if depth != 0 || (i > 0 && isWordByte(text[i-1])) {
+			continue
+		}
+		for _, keyword := range keywords {
+			end := i + len(keyword)
+			if end > len(text) || !strings.EqualFold(text[i:end], keyword) || (end < len(text) && isWordByte(text[end])) {
+				continue
+			}
+			result = append(result, &keywordPosition{keyword: keyword, index: i})
+			i = end - 1
+			break
+		}
+	}
+	return result
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || c == '`' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parseCast parses call arguments of CAST(expression AS type) or EXTRACT(part FROM expression),
+// the last top level keyword separates the arguments
+func parseCast(raw string, keyword string) (*castExpr, error) {
+	key := keyword + raw
+	if cached, ok := parsedExpressions.Load(key); ok {
+		return cached.(*castExpr), nil
+	}
+	args := strings.TrimSpace(raw)
+	args = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(args, "("), ")"))
+	positions := keywordPositions(args, keyword)
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("invalid arguments: %s, %s expected", raw, keyword)
+	}
+	index := positions[len(positions)-1].index
+	left, right := strings.TrimSpace(args[:index]), strings.TrimSpace(args[index+len(keyword):])
+	result := &castExpr{}
+	var err error
+	if keyword == "AS" {
+		result.typeName = strings.ToUpper(right)
+		result.x, err = parseExpression(left)
+	} else {
+		result.typeName = strings.ToUpper(left)
+		result.x, err = parseExpression(right)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %s, %v", raw, err)
+	}
+	parsedExpressions.Store(key, result)
+	return result, nil
+}
+
+// evaluateCast evaluates CAST(expression AS type)
+func evaluateCast(call *expr.Call, record map[string]interface{}) (interface{}, error) {
+	cast, err := parseCast(call.Raw, "AS")
+	if err != nil {
+		return nil, fmt.Errorf("CAST %v", err)
+	}
+	value, err := Evaluate(cast.x, record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	return Cast(value, cast.typeName)
+}
+
+// evaluateExtract evaluates EXTRACT(part FROM expression)
+func evaluateExtract(call *expr.Call, record map[string]interface{}) (interface{}, error) {
+	extract, err := parseCast(call.Raw, "FROM")
+	if err != nil {
+		return nil, fmt.Errorf("EXTRACT %v", err)
+	}
+	value, err := Evaluate(extract.x, record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	fn, ok := functions[extract.typeName]
+	if !ok || !dateParts[extract.typeName] {
+		return nil, fmt.Errorf("unsupported EXTRACT part: %s", extract.typeName)
+	}
+	return fn([]interface{}{value})
+}
+
+// operands returns operand, ELSE, WHEN and THEN expressions of CASE expression
+func (aCase *caseExpr) operands() []node.Node {
+	result := append([]node.Node{aCase.operand, aCase.orElse}, aCase.whens...)
+	return append(result, aCase.thens...)
+}
+
+// rawOperands returns operands of expressions parsed from raw SQL text: CASE, CAST and EXTRACT,
+// false is returned for other expressions
+func rawOperands(n node.Node) ([]node.Node, bool, error) {
+	switch actual := n.(type) {
+	case *expr.Switch:
+		aCase, err := parseCase(actual.Raw)
+		if err != nil {
+			return nil, true, err
+		}
+		return aCase.operands(), true, nil
+	case *caseExpr:
+		return actual.operands(), true, nil
+	case *expr.Call:
+		keyword := ""
+		switch strings.ToUpper(sqlparser.Stringify(actual.X)) {
+		case "CAST":
+			keyword = "AS"
+		case "EXTRACT":
+			keyword = "FROM"
+		default:
+			return nil, false, nil
+		}
+		cast, err := parseCast(actual.Raw, keyword)
+		if err != nil {
+			return nil, true, err
+		}
+		return []node.Node{cast.x}, true, nil
+	}
+	return nil, false, nil
+}
+
+// Cast converts value into SQL type: STRING, INT64, FLOAT64, BOOL, TIMESTAMP or DATE (and their common synonyms)
+func Cast(value interface{}, typeName string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if index := strings.Index(typeName, "("); index != -1 { // i.e. VARCHAR(20)
+		typeName = strings.TrimSpace(typeName[:index])
+	}
+	switch strings.ToUpper(typeName) {
+	case "STRING", "VARCHAR", "CHAR", "TEXT":
+		if ts, ok := value.(time.Time); ok {
+			return ts.Format(time.RFC3339Nano), nil
+		}
+		if data, ok := value.([]byte); ok {
+			return string(data), nil
+		}
+		return fmt.Sprintf("%v", value), nil
+	case "INT", "INT64", "INTEGER", "BIGINT", "SMALLINT":
+		switch actual := value.(type) {
+		case bool:
+			if actual {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			result, err := strconv.ParseInt(strings.TrimSpace(actual), 10, 64)
+			if err != nil {
+				if f, ferr := strconv.ParseFloat(strings.TrimSpace(actual), 64); ferr == nil {
+					return int64(f), nil
+				}
+				return nil, fmt.Errorf("failed to cast %q to %s: %v", actual, typeName, err)
+			}
+			return result, nil
+		case time.Time:
+			return actual.Unix(), nil
+		}
+		if result, ok := asInt64(value); ok {
+			return result, nil
+		}
+		if result, ok := AsFloat64(value); ok {
+			return int64(result), nil
+		}
+	case "FLOAT", "FLOAT64", "DOUBLE", "REAL", "NUMERIC", "DECIMAL":
+		if text, ok := value.(string); ok {
+			result, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to cast %q to %s: %v", text, typeName, err)
+			}
+			return result, nil
+		}
+		if result, ok := AsFloat64(value); ok {
+			return result, nil
+		}
+	case "BOOL", "BOOLEAN":
+		switch actual := value.(type) {
+		case bool:
+			return actual, nil
+		case string:
+			result, err := strconv.ParseBool(strings.TrimSpace(actual))
+			if err != nil {
+				return nil, fmt.Errorf("failed to cast %q to %s: %v", actual, typeName, err)
+			}
+			return result, nil
+		}
+		if result, ok := AsFloat64(value); ok {
+			return result != 0, nil
+		}
+	case "TIMESTAMP", "DATETIME":
+		return AsTime(value)
+	case "DATE":
+		ts, err := AsTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return truncateDay(ts), nil
+	default:
+		return nil, fmt.Errorf("unsupported CAST type: %s", typeName)
+	}
+	return nil, fmt.Errorf("failed to cast %T to %s", value, typeName)
+}
+
+// timeLayouts represents layouts of timestamps represented as text
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"}
+
+// AsTime converts timestamp value or text into time.Time
+func AsTime(value interface{}) (time.Time, error) {
+	switch actual := value.(type) {
+	case time.Time:
+		return actual, nil
+	case *time.Time:
+		if actual != nil {
+			return *actual, nil
+		}
+	case string:
+		for _, layout := range timeLayouts {
+			if ts, err := time.Parse(layout, strings.TrimSpace(actual)); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid timestamp: %q", actual)
+	}
+	return time.Time{}, fmt.Errorf("expected timestamp, but had: %T", value)
+}
+
+func truncateDay(ts time.Time) time.Time {
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, ts.Location())
+}
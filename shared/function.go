@@ -0,0 +1,247 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Function represents a scalar SQL function evaluated client side
+type Function func(args []interface{}) (interface{}, error)
+
+// functions represents scalar functions registry keyed by upper-cased name
+var functions = map[string]Function{
+	"LOWER": stringFunction(strings.ToLower),
+	"UPPER": stringFunction(strings.ToUpper),
+	"TRIM":  stringFunction(strings.TrimSpace),
+	"LTRIM": stringFunction(func(s string) string { return strings.TrimLeft(s, " \t\r\n") }),
+	"RTRIM": stringFunction(func(s string) string { return strings.TrimRight(s, " \t\r\n") }),
+	"LENGTH": func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("LENGTH", args, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		if text, ok := args[0].(string); ok {
+			return int64(utf8.RuneCountInString(text)), nil
+		}
+		return int64(len(ExpandSlice(args[0]))), nil
+	},
+	"CONCAT": func(args []interface{}) (interface{}, error) {
+		builder := strings.Builder{}
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+			builder.WriteString(fmt.Sprintf("%v", arg))
+		}
+		return builder.String(), nil
+	},
+	"COALESCE": func(args []interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	},
+	"ABS": func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("ABS", args, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		if value, ok := asInt64(args[0]); ok {
+			if value < 0 {
+				return -value, nil
+			}
+			return value, nil
+		}
+		value, ok := AsFloat64(args[0])
+		if !ok {
+			return nil, fmt.Errorf("ABS expects numeric argument, but had: %T", args[0])
+		}
+		return math.Abs(value), nil
+	},
+	"SUBSTR":            substr,
+	"SUBSTRING":         substr,
+	"NOW":               now,
+	"CURRENT_TIMESTAMP": now,
+	"CURRENT_DATE": func(args []interface{}) (interface{}, error) {
+		return truncateDay(time.Now().UTC()), expectArgs("CURRENT_DATE", args, 0)
+	},
+	"DATE":         timeFunction("DATE", func(ts time.Time) interface{} { return truncateDay(ts) }),
+	"YEAR":         timeFunction("YEAR", func(ts time.Time) interface{} { return int64(ts.Year()) }),
+	"MONTH":        timeFunction("MONTH", func(ts time.Time) interface{} { return int64(ts.Month()) }),
+	"DAY":          timeFunction("DAY", func(ts time.Time) interface{} { return int64(ts.Day()) }),
+	"HOUR":         timeFunction("HOUR", func(ts time.Time) interface{} { return int64(ts.Hour()) }),
+	"MINUTE":       timeFunction("MINUTE", func(ts time.Time) interface{} { return int64(ts.Minute()) }),
+	"SECOND":       timeFunction("SECOND", func(ts time.Time) interface{} { return int64(ts.Second()) }),
+	"DAYOFWEEK":    timeFunction("DAYOFWEEK", func(ts time.Time) interface{} { return int64(ts.Weekday()) + 1 }),
+	"DAYOFYEAR":    timeFunction("DAYOFYEAR", func(ts time.Time) interface{} { return int64(ts.YearDay()) }),
+	"UNIX_SECONDS": timeFunction("UNIX_SECONDS", func(ts time.Time) interface{} { return ts.Unix() }),
+	"UNIX_MILLIS":  timeFunction("UNIX_MILLIS", func(ts time.Time) interface{} { return ts.UnixMilli() }),
+	"DATE_FORMAT": func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("DATE_FORMAT", args, 2); err != nil || args[0] == nil || args[1] == nil {
+			return nil, err
+		}
+		ts, err := AsTime(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("DATE_FORMAT %v", err)
+		}
+		return formatTime(ts, fmt.Sprintf("%v", args[1])), nil
+	},
+}
+
+// dateParts represents date functions usable as EXTRACT(part FROM timestamp)
+var dateParts = map[string]bool{"YEAR": true, "MONTH": true, "DAY": true, "HOUR": true, "MINUTE": true, "SECOND": true, "DAYOFWEEK": true, "DAYOFYEAR": true, "DATE": true}
+
+// substr returns substring of 1-based position and optional length
+func substr(args []interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("SUBSTR expects 2 or 3 arguments, but had: %v", len(args))
+	}
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+	text := []rune(fmt.Sprintf("%v", args[0]))
+	position, ok := asInt64(args[1])
+	if !ok {
+		return nil, fmt.Errorf("SUBSTR expects integer position, but had: %T", args[1])
+	}
+	start := int(position) - 1
+	if position < 0 { // negative position counts from the end
+		start = len(text) + int(position)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > len(text) {
+		start = len(text)
+	}
+	end := len(text)
+	if len(args) == 3 {
+		length, ok := asInt64(args[2])
+		if !ok || length < 0 {
+			return nil, fmt.Errorf("SUBSTR expects non-negative integer length, but had: %v", args[2])
+		}
+		if start+int(length) < end {
+			end = start + int(length)
+		}
+	}
+	return string(text[start:end]), nil
+}
+
+func now(args []interface{}) (interface{}, error) {
+	return time.Now().UTC(), expectArgs("NOW", args, 0)
+}
+
+func timeFunction(name string, fn func(ts time.Time) interface{}) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if err := expectArgs(name, args, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		ts, err := AsTime(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s %v", name, err)
+		}
+		return fn(ts), nil
+	}
+}
+
+// timeFormat maps MySQL DATE_FORMAT specifiers to Go layouts
+var timeFormat = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'c': "1", 'd': "02", 'e': "2", 'H': "15", 'h': "03", 'I': "03",
+	'i': "04", 's': "05", 'S': "05", 'f': "000000", 'M': "January", 'b': "Jan", 'W': "Monday", 'a': "Mon", 'p': "PM",
+}
+
+// formatTime formats timestamp with MySQL DATE_FORMAT specifiers, i.e. %Y-%m-%d
+func formatTime(ts time.Time, format string) string {
+	var builder strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			builder.WriteByte(format[i])
+			continue
+		}
+		i++
+		if layout, ok := timeFormat[format[i]]; ok {
+			builder.WriteString(ts.Format(layout))
+			continue
+		}
+		builder.WriteByte(format[i])
+	}
+	return builder.String()
+}
+
+func stringFunction(fn func(string) string) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument, but had: %v", len(args))
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		return fn(fmt.Sprintf("%v", args[0])), nil
+	}
+}
+
+func expectArgs(name string, args []interface{}, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("%s expects %v argument(s), but had: %v", name, count, len(args))
+	}
+	return nil
+}
+
+// arithmetic evaluates arithmetic operator, integer operands produce an integer result
+func arithmetic(op string, x, y interface{}) (interface{}, error) {
+	if x == nil || y == nil {
+		return nil, nil
+	}
+	if xInt, ok := asInt64(x); ok {
+		if yInt, ok := asInt64(y); ok {
+			switch op {
+			case "+":
+				return xInt + yInt, nil
+			case "-":
+				return xInt - yInt, nil
+			case "*":
+				return xInt * yInt, nil
+			case "/", "%":
+				if yInt == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				if op == "%" {
+					return xInt % yInt, nil
+				}
+				return xInt / yInt, nil
+			}
+		}
+	}
+	xFloat, ok := AsFloat64(x)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s operand: %T", op, x)
+	}
+	yFloat, ok := AsFloat64(y)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s operand: %T", op, y)
+	}
+	switch op {
+	case "+":
+		return xFloat + yFloat, nil
+	case "-":
+		return xFloat - yFloat, nil
+	case "*":
+		return xFloat * yFloat, nil
+	case "/":
+		if yFloat == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return xFloat / yFloat, nil
+	case "%":
+		if yFloat == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(xFloat, yFloat), nil
+	}
+	return nil, fmt.Errorf("unsupported arithmetic operator: %s", op)
+}
@@ -0,0 +1,149 @@
+package shared
+
+import (
+	"strings"
+
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+const (
+	precedenceOr = iota + 1
+	precedenceAnd
+	precedenceNot
+	precedenceComparison
+	precedenceAdditive
+	precedenceMultiplicative
+)
+
+// token represents either an operand or an operator of a flattened expression
+type token struct {
+	operand  node.Node
+	operator string
+	prefix   bool
+}
+
+// Normalize rebuilds operator precedence of a parsed expression.
+// sqlparser produces right-leaning binary chains, i.e. "a = 1 AND b = 2" is parsed as "a = (1 AND (b = 2))",
+// Normalize flattens such chains and regroups them, so that OR binds loosest, followed by AND, NOT,
+// comparison, additive and multiplicative operators. Operators are upper-cased, parenthesis are preserved.
+func Normalize(n node.Node) node.Node {
+	if n == nil {
+		return nil
+	}
+	var tokens []*token
+	flatten(n, &tokens)
+	if len(tokens) == 0 {
+		return n
+	}
+	p := &precedenceParser{tokens: tokens}
+	return p.parse(precedenceOr)
+}
+
+// Unwrap returns expression enclosed in parenthesis
+func Unwrap(n node.Node) node.Node {
+	for {
+		parenthesis, ok := n.(*expr.Parenthesis)
+		if !ok {
+			return n
+		}
+		inner, ok := parenthesis.X.(node.Node)
+		if !ok || inner == nil {
+			return n
+		}
+		if _, ok := inner.([]node.Node); ok {
+			return n
+		}
+		n = inner
+	}
+}
+
+func flatten(n node.Node, tokens *[]*token) {
+	switch actual := n.(type) {
+	case *expr.Binary:
+		if actual.X != nil {
+			flatten(actual.X, tokens)
+		}
+		if actual.Op == "" || actual.Y == nil {
+			return
+		}
+		*tokens = append(*tokens, &token{operator: normalizeOperator(actual.Op)})
+		flatten(actual.Y, tokens)
+	case *expr.Unary:
+		if strings.EqualFold(actual.Op, "NOT") {
+			*tokens = append(*tokens, &token{operator: "NOT", prefix: true})
+			flatten(actual.X, tokens)
+			return
+		}
+		*tokens = append(*tokens, &token{operand: &expr.Unary{Op: actual.Op, X: Normalize(actual.X)}})
+	case *expr.Parenthesis:
+		if inner, ok := actual.X.(node.Node); ok && inner != nil {
+			if _, isList := inner.([]node.Node); !isList {
+				*tokens = append(*tokens, &token{operand: &expr.Parenthesis{Raw: actual.Raw, X: Normalize(inner)}})
+				return
+			}
+		}
+		*tokens = append(*tokens, &token{operand: actual})
+	case *expr.Call:
+		args := make([]node.Node, len(actual.Args))
+		for i, arg := range actual.Args {
+			args[i] = Normalize(arg)
+		}
+		*tokens = append(*tokens, &token{operand: &expr.Call{X: actual.X, Raw: actual.Raw, Args: args}})
+	default:
+		*tokens = append(*tokens, &token{operand: n})
+	}
+}
+
+func normalizeOperator(op string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(op)), " ")
+}
+
+func precedence(op string) int {
+	switch op {
+	case "OR":
+		return precedenceOr
+	case "AND":
+		return precedenceAnd
+	case "+", "-":
+		return precedenceAdditive
+	case "*", "/", "%":
+		return precedenceMultiplicative
+	}
+	return precedenceComparison
+}
+
+type precedenceParser struct {
+	tokens []*token
+	pos    int
+}
+
+func (p *precedenceParser) parse(minPrecedence int) node.Node {
+	left := p.parsePrefix()
+	for p.pos < len(p.tokens) {
+		next := p.tokens[p.pos]
+		if next.operator == "" || next.prefix {
+			break
+		}
+		opPrecedence := precedence(next.operator)
+		if opPrecedence < minPrecedence {
+			break
+		}
+		p.pos++
+		right := p.parse(opPrecedence + 1)
+		left = &expr.Binary{X: left, Op: next.operator, Y: right}
+	}
+	return left
+}
+
+func (p *precedenceParser) parsePrefix() node.Node {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	current := p.tokens[p.pos]
+	p.pos++
+	if current.prefix {
+		return &expr.Unary{Op: current.operator, X: p.parse(precedenceNot)}
+	}
+	return current.operand
+}
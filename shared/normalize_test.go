@@ -0,0 +1,59 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+// grouped renders an expression with binary and unary operations parenthesized
+func grouped(n node.Node) string {
+	switch actual := n.(type) {
+	case *expr.Binary:
+		return "(" + grouped(actual.X) + " " + actual.Op + " " + grouped(actual.Y) + ")"
+	case *expr.Unary:
+		return "(" + actual.Op + " " + grouped(actual.X) + ")"
+	case *expr.Call:
+		args := make([]string, len(actual.Args))
+		for i, arg := range actual.Args {
+			args[i] = grouped(arg)
+		}
+		return sqlparser.Stringify(actual.X) + "(" + strings.Join(args, ", ") + ")"
+	case *expr.Parenthesis:
+		if inner, ok := actual.X.(node.Node); ok {
+			if _, isList := inner.([]node.Node); !isList {
+				return grouped(inner)
+			}
+		}
+	}
+	return sqlparser.Stringify(n)
+}
+
+func TestNormalize(t *testing.T) {
+	var testCases = []struct {
+		description string
+		where       string
+		expect      string
+	}{
+		{description: "comparisons bind tighter than AND", where: "a = 1 AND b = 2", expect: "((a = 1) AND (b = 2))"},
+		{description: "AND binds tighter than OR", where: "a = 1 OR b = 2 AND c = 3", expect: "((a = 1) OR ((b = 2) AND (c = 3)))"},
+		{description: "OR of ANDs", where: "a = 1 AND b = 2 OR c = 3 AND d = 4", expect: "(((a = 1) AND (b = 2)) OR ((c = 3) AND (d = 4)))"},
+		{description: "parenthesis are preserved", where: "(a = 1 OR b = 2) AND c = 3", expect: "(((a = 1) OR (b = 2)) AND (c = 3))"},
+		{description: "NOT binds tighter than AND", where: "NOT a = 1 AND b = 2", expect: "((NOT (a = 1)) AND (b = 2))"},
+		{description: "arithmetic binds tighter than comparison", where: "a + 2 * b > 3 OR c - 1 < d / 2", expect: "(((a + (2 * b)) > 3) OR ((c - 1) < (d / 2)))"},
+		{description: "operators are upper-cased", where: "a = 1 or b in (1, 2) and c not in (3)", expect: "((a = 1) OR ((b IN (1, 2)) AND (c NOT IN (3))))"},
+		{description: "call arguments are normalized", where: "COALESCE(a + 1 * 2, 0) = 3 AND b = 4", expect: "((COALESCE((a + (1 * 2)), 0) = 3) AND (b = 4))"},
+	}
+	for _, testCase := range testCases {
+		query, err := sqlparser.ParseQuery("SELECT * FROM t WHERE " + testCase.where)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.description, err)
+		}
+		if actual := grouped(Normalize(query.Qualify.X)); actual != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.description, testCase.expect, actual)
+		}
+	}
+}
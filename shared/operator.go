@@ -0,0 +1,29 @@
+package shared
+
+import "strings"
+
+// ReplaceOperators rewrites SQL operators unsupported by sqlparser into their supported equivalent, i.e. <> into !=
+func ReplaceOperators(SQL string) string {
+	if !strings.Contains(SQL, "<>") {
+		return SQL
+	}
+	var builder strings.Builder
+	var quote byte
+	for i := 0; i < len(SQL); i++ {
+		c := SQL[i]
+		switch {
+		case quote != 0:
+			if c == quote && SQL[i-1] != '\\' {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '<' && i+1 < len(SQL) && SQL[i+1] == '>':
+			builder.WriteString("!=")
+			i++
+			continue
+		}
+		builder.WriteByte(c)
+	}
+	return builder.String()
+}
@@ -0,0 +1,148 @@
+package shared
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/viant/sqlparser"
+	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
+)
+
+const (
+	escapedPrefix = "Qx"
+	escapedSuffix = "xQ"
+)
+
+// EscapeFieldPaths rewrites backtick-quoted field path segments, i.e. `meta`.`a-b` or `x.y`,
+// into plain identifiers sqlparser can parse; FieldPath restores the original segments
+func EscapeFieldPaths(SQL string) string {
+	if !strings.Contains(SQL, "`") {
+		return SQL
+	}
+	var builder strings.Builder
+	var quote byte
+	for i := 0; i < len(SQL); i++ {
+		c := SQL[i]
+		switch {
+		case quote != 0:
+			if c == quote && SQL[i-1] != '\\' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '`':
+			end := strings.IndexByte(SQL[i+1:], '`')
+			if end == -1 {
+				break
+			}
+			end += i + 1
+			segment := SQL[i+1 : end]
+			isPath := strings.Contains(segment, ".") || (i > 0 && SQL[i-1] == '.') || (end+1 < len(SQL) && SQL[end+1] == '.')
+			if !isPath {
+				builder.WriteString(SQL[i : end+1])
+				i = end
+				continue
+			}
+			builder.WriteString(escapedPrefix + hex.EncodeToString([]byte(segment)) + escapedSuffix)
+			i = end
+			continue
+		}
+		builder.WriteByte(c)
+	}
+	return builder.String()
+}
+
+// FieldPath splits a column expression into field path segments, i.e. address.city into [address city],
+// backtick quotes are removed and escaped segments restored
+func FieldPath(column string) []string {
+	var result []string
+	var segment strings.Builder
+	quoted := false
+	for i := 0; i < len(column); i++ {
+		c := column[i]
+		switch {
+		case c == '`':
+			quoted = !quoted
+			continue
+		case c == '.' && !quoted:
+			result = append(result, unescapeSegment(segment.String()))
+			segment.Reset()
+			continue
+		}
+		segment.WriteByte(c)
+	}
+	return append(result, unescapeSegment(segment.String()))
+}
+
+// ColumnName returns display name of a column expression
+func ColumnName(column string) string {
+	return strings.Join(FieldPath(column), ".")
+}
+
+func unescapeSegment(segment string) string {
+	if !strings.HasPrefix(segment, escapedPrefix) || !strings.HasSuffix(segment, escapedSuffix) || len(segment) < len(escapedPrefix)+len(escapedSuffix) {
+		return segment
+	}
+	decoded, err := hex.DecodeString(segment[len(escapedPrefix) : len(segment)-len(escapedSuffix)])
+	if err != nil {
+		return segment
+	}
+	return string(decoded)
+}
+
+// Columns returns columns (identifiers and field paths) referenced by an expression,
+// false is returned if the expression has nodes columns can not be determined for
+func Columns(n node.Node) ([]string, bool) {
+	if operands, ok, err := rawOperands(n); ok {
+		if err != nil {
+			return nil, false
+		}
+		return columnsOf(operands...)
+	}
+	var result []string
+	switch actual := n.(type) {
+	case nil, *expr.Literal, *expr.Placeholder, *Bound:
+	case *expr.Ident, *expr.Selector:
+		result = append(result, sqlparser.Stringify(actual))
+	case *expr.Binary:
+		return columnsOf(actual.X, actual.Y)
+	case *expr.Unary:
+		return Columns(actual.X)
+	case *expr.Parenthesis:
+		inner, ok := actual.X.(node.Node)
+		if !ok {
+			return nil, false
+		}
+		return Columns(inner)
+	case []node.Node:
+		return columnsOf(actual...)
+	case *expr.Range:
+		return columnsOf(actual.Min, actual.Max)
+	case *expr.Call:
+		if strings.Contains(strings.ToUpper(actual.Raw), "DISTINCT") {
+			return nil, false // COUNT(DISTINCT column) argument is not parsed
+		}
+		if len(actual.Args) == 1 {
+			if _, ok := actual.Args[0].(*expr.Star); ok {
+				return nil, true
+			}
+		}
+		return columnsOf(actual.Args...)
+	default:
+		return nil, false
+	}
+	return result, true
+}
+
+func columnsOf(nodes ...node.Node) ([]string, bool) {
+	var result []string
+	for _, item := range nodes {
+		columns, ok := Columns(item)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, columns...)
+	}
+	return result, true
+}
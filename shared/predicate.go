@@ -1,12 +1,12 @@
 package shared
 
 import (
-	"github.com/viant/sqlparser"
 	"github.com/viant/sqlparser/expr"
+	"github.com/viant/sqlparser/node"
 	"github.com/viant/sqlparser/query"
-	"strings"
 )
 
+// IsFalsePredicate returns true if the predicate has a comparison of different literals, i.e. 1 = 0
 func IsFalsePredicate(binary *expr.Binary) bool {
 	if binary.Op == "=" {
 		if leftLiteral, ok := binary.X.(*expr.Literal); ok {
@@ -15,8 +15,18 @@ func IsFalsePredicate(binary *expr.Binary) bool {
 			}
 		}
 	}
-	where := sqlparser.Stringify(binary)
-	return strings.Contains(where, "1=0") || strings.Contains(where, "1 = 0")
+	return hasFalsePredicate(binary.X) || hasFalsePredicate(binary.Y)
+}
+
+// hasFalsePredicate walks binary and parenthesized expressions, sqlparser.Stringify does not support i.e. CASE expressions
+func hasFalsePredicate(n node.Node) bool {
+	switch actual := n.(type) {
+	case *expr.Binary:
+		return IsFalsePredicate(actual)
+	case *expr.Parenthesis:
+		return hasFalsePredicate(actual.X)
+	}
+	return false
 }
 
 func IsDryRun(selectStmt *query.Select) bool {
@@ -0,0 +1,31 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/viant/sqlparser"
+)
+
+func TestIsDryRun(t *testing.T) {
+	var testCases = []struct {
+		SQL    string
+		expect bool
+	}{
+		{SQL: "SELECT * FROM t WHERE 1 = 0", expect: true},
+		{SQL: "SELECT * FROM t WHERE 1 = 1"},
+		{SQL: "SELECT * FROM t WHERE a = ? AND (1 = 0)", expect: true},
+		{SQL: "SELECT * FROM t WHERE a = 1"},
+		{SQL: "SELECT * FROM t"},
+		{SQL: "SELECT * FROM t WHERE CASE WHEN a = ? THEN 1 ELSE 0 END = ? AND 1 = 0", expect: true},
+		{SQL: "SELECT * FROM t WHERE CASE WHEN a = ? THEN 1 ELSE 0 END = ?"},
+	}
+	for _, testCase := range testCases {
+		query, err := sqlparser.ParseQuery(testCase.SQL)
+		if err != nil {
+			t.Fatalf("%v: %v", testCase.SQL, err)
+		}
+		if actual := IsDryRun(query); actual != testCase.expect {
+			t.Errorf("%v: expected %v, but had %v", testCase.SQL, testCase.expect, actual)
+		}
+	}
+}
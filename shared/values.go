@@ -3,6 +3,8 @@ package shared
 import (
 	"database/sql/driver"
 	"fmt"
+	"reflect"
+	"sort"
 )
 
 // Values represents value slice
@@ -15,3 +17,48 @@ func (v Values) Named() []driver.NamedValue {
 	}
 	return result
 }
+
+// ExpandSlice returns slice items or the value itself if it is not a slice
+func ExpandSlice(value interface{}) []interface{} {
+	if value == nil {
+		return []interface{}{nil}
+	}
+	if values, ok := value.([]interface{}); ok {
+		return values
+	}
+	if _, ok := value.([]byte); ok {
+		return []interface{}{value}
+	}
+	rValue := reflect.ValueOf(value)
+	if rValue.Kind() != reflect.Slice && rValue.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	var result = make([]interface{}, rValue.Len())
+	for i := range result {
+		result[i] = rValue.Index(i).Interface()
+	}
+	return result
+}
+
+// StarColumns returns SELECT * columns: union of record fields, leading columns first (if present in any record), then the other fields in alphabetical order
+func StarColumns(records []map[string]interface{}, leading ...string) []string {
+	var fields = make(map[string]bool)
+	for _, record := range records {
+		for field := range record {
+			fields[field] = true
+		}
+	}
+	var result = make([]string, 0, len(fields))
+	for _, column := range leading {
+		if fields[column] {
+			result = append(result, column)
+			delete(fields, column)
+		}
+	}
+	var others = make([]string, 0, len(fields))
+	for field := range fields {
+		others = append(others, field)
+	}
+	sort.Strings(others)
+	return append(result, others...)
+}